@@ -9,7 +9,11 @@ import (
 // contextKey avoids naming collision. Set the user context as type: contextKey.
 type contextKey string
 
-const userContextKey = contextKey("users")
+const (
+	userContextKey          = contextKey("users")
+	tokenContextKey         = contextKey("token")
+	runtimeFormatContextKey = contextKey("runtime_format")
+)
 
 // Return a new Context with User embedded in the contextKey.
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
@@ -27,3 +31,31 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+// Return a new Context with the plaintext of the authentication token the user was authenticated with embedded.
+func (app *application) contextSetToken(r *http.Request, token string) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey, token)
+	return r.WithContext(ctx)
+}
+
+// Retrieve the plaintext of the authentication token of the request, empty for an anonymous user.
+func (app *application) contextGetToken(r *http.Request) string {
+	token, _ := r.Context().Value(tokenContextKey).(string)
+	return token
+}
+
+// Return a new Context with the runtime format chosen for the response embedded.
+func (app *application) contextSetRuntimeFormat(r *http.Request, format data.RuntimeFormat) *http.Request {
+	ctx := context.WithValue(r.Context(), runtimeFormatContextKey, format)
+	return r.WithContext(ctx)
+}
+
+// Retrieve the runtime format chosen for the response, the default when none was chosen.
+func (app *application) contextGetRuntimeFormat(r *http.Request) data.RuntimeFormat {
+	format, ok := r.Context().Value(runtimeFormatContextKey).(data.RuntimeFormat)
+	if !ok {
+		return data.RuntimeFormatMins
+	}
+
+	return format
+}
@@ -0,0 +1,21 @@
+//go:build go1.20
+// +build go1.20
+
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// clearWriteDeadline lifts the server's WriteTimeout for the current response, for handlers which stream for longer
+// than a normal request such as exportMoviesHandler.
+func clearWriteDeadline(w http.ResponseWriter) error {
+	return http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
+
+// clearReadDeadline lifts the server's ReadTimeout for the current request, for handlers which read a body for longer
+// than a normal request such as importMoviesHandler.
+func clearReadDeadline(w http.ResponseWriter) error {
+	return http.NewResponseController(w).SetReadDeadline(time.Time{})
+}
@@ -0,0 +1,19 @@
+//go:build !go1.20
+// +build !go1.20
+
+package main
+
+import (
+	"net/http"
+)
+
+// clearWriteDeadline is a no-op before Go 1.20, which cannot change the deadline of a single response. Streaming
+// responses remain bounded by the server's WriteTimeout.
+func clearWriteDeadline(w http.ResponseWriter) error {
+	return nil
+}
+
+// clearReadDeadline is a no-op before Go 1.20, so streamed request bodies remain bounded by the server's ReadTimeout.
+func clearReadDeadline(w http.ResponseWriter) error {
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"movieDB/internal/data"
 	"net/http"
 )
 
@@ -56,6 +57,36 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+// patchConflictResponse sends a 409 for a patch which cannot be applied to the record as it stands, such as one
+// whose test operation fails or which refers to an array element that does not exist.
+func (app *application) patchConflictResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusConflict, err.Error())
+}
+
+// duplicateMovieResponse sends a 409 listing the existing movies which a new movie appears to duplicate.
+func (app *application) duplicateMovieResponse(w http.ResponseWriter, r *http.Request, candidates []*data.Movie) {
+	env := envelope{
+		"error":      "a movie with a near-identical title and the same year already exists, pass force=true to create it anyway",
+		"candidates": candidates,
+	}
+
+	err := app.writeJSON(w, http.StatusConflict, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record has changed since it was read, fetch it again to get the current ETag"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "an If-Match header holding the record's ETag is required"
+	app.errorResponse(w, r, http.StatusPreconditionRequired, message)
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rateLimitExceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
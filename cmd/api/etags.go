@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"movieDB/internal/data"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// movieETag returns the entity tag of a movie as it is written in a response, after presentMovies. The tag is a
+// digest of the JSON written, so it changes with anything the response holds: the edited fields and version, but
+// also the aggregate rating, the poster, the title chosen by Accept-Language, the runtime format, the field set and
+// included resources, none of which change the version. The version leads the tag to make it easier to read.
+func movieETag(movie *data.Movie) (string, error) {
+	js, err := json.Marshal(movie)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(js)
+	return strconv.Quote(fmt.Sprintf("%d-%x", movie.Version, sum[:8])), nil
+}
+
+// currentMovieETag returns the tag a GET of the movie without a field set would send in response to r, for
+// comparing with the If-Match header of a request changing the movie. The movie itself is left as it is.
+func (app *application) currentMovieETag(w http.ResponseWriter, r *http.Request, movie *data.Movie) (string, error) {
+	presented := *movie
+	err := app.presentMovies(w, r, fieldset{}, &presented)
+	if err != nil {
+		return "", err
+	}
+	return movieETag(&presented)
+}
+
+// etagMatches reports whether header, the value of an If-Match or If-None-Match header, lists etag or is "*". With
+// weak set, a weak tag in header matches the strong tag it is based on, as If-None-Match requires; If-Match uses
+// strong comparison, where weak tags never match.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified answers a conditional GET. When the If-None-Match header of the request matches etag a 304 is sent
+// and true returned, leaving nothing more for the handler to do.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagMatches(header, etag, true) {
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// checkIfMatch enforces the If-Match header of a request which changes the resource tagged etag, sending a 412 when
+// it does not match. Without the header the request goes ahead, unless -etag-strict is set in which case a 428 is
+// sent. It returns false when a response has been sent.
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	switch {
+	case header == "" && app.config.etag.strict:
+		app.preconditionRequiredResponse(w, r)
+		return false
+	case header != "" && !etagMatches(header, etag, false):
+		app.preconditionFailedResponse(w, r)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		weak   bool
+		want   bool
+	}{
+		{`"1-ab"`, `"1-ab"`, false, true},
+		{`"1-ab"`, `"2-cd"`, false, false},
+		{`"0-00", "1-ab"`, `"1-ab"`, false, true},
+		{`*`, `"1-ab"`, false, true},
+		{`W/"1-ab"`, `"1-ab"`, false, false},
+		{`W/"1-ab"`, `"1-ab"`, true, true},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, tt.etag, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%q, %q, %v) = %v; want %v", tt.header, tt.etag, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestShowMovieNotModified(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	id := ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+	path := fmt.Sprintf("/v1/movies/%d", id)
+
+	etag := ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil).Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag sent")
+	}
+
+	res := ts.request(t, http.MethodGet, path, "", "If-None-Match", etag)
+	if res.StatusCode != http.StatusNotModified {
+		t.Fatalf("got status %d for an unchanged movie; want %d", res.StatusCode, http.StatusNotModified)
+	}
+
+	// A rating changes the aggregate rating written with the movie but not its version.
+	ts.requestJSON(t, http.MethodPost, path+"/ratings", `{"score":8}`, http.StatusCreated, nil)
+
+	rated := ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil, "If-None-Match", etag).Get("ETag")
+	if rated == etag {
+		t.Errorf("ETag %s unchanged by a new rating", etag)
+	}
+
+	// The representation, so the tag, also varies with the request.
+	ts.requestJSON(t, http.MethodPost, path+"/titles", `{"title":"Vaiana","language":"fr"}`, http.StatusCreated, nil)
+	for _, headers := range [][]string{
+		{"Accept", "application/json; runtime=hm"},
+		{"Accept-Language", "fr"},
+	} {
+		got := ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil, headers...).Get("ETag")
+		if got == rated {
+			t.Errorf("ETag unchanged by %s: %s", headers[0], headers[1])
+		}
+	}
+
+	limited := ts.requestJSON(t, http.MethodGet, path+"?fields=title", "", http.StatusOK, nil, "If-None-Match", rated).Get("ETag")
+	if limited == rated {
+		t.Errorf("ETag unchanged by a field set")
+	}
+}
+
+func TestUpdateMovieIfMatch(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	id := ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+	path := fmt.Sprintf("/v1/movies/%d", id)
+
+	etag := ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil).Get("ETag")
+	ts.requestJSON(t, http.MethodPost, path+"/ratings", `{"score":8}`, http.StatusCreated, nil)
+
+	// The rating changed the movie since the tag was read.
+	ts.requestJSON(t, http.MethodPatch, path, `{"year":2017}`, http.StatusPreconditionFailed, nil, "If-Match", etag)
+
+	etag = ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil).Get("ETag")
+	updated := ts.requestJSON(t, http.MethodPatch, path, `{"year":2017}`, http.StatusOK, nil, "If-Match", etag).Get("ETag")
+
+	current := ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil).Get("ETag")
+	if updated != current {
+		t.Errorf("update sent ETag %s; GET sends %s", updated, current)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFlushRows is the number of rows written between flushes of an export to the client.
+const exportFlushRows = 500
+
+// exportMoviesHandler streams every movie matching the listing filters as NDJSON (the default) or CSV. Unlike
+// listMoviesHandler the response is not paginated or held in memory: rows are written as they are read from the
+// database and flushed to the client every exportFlushRows rows. Both formats carry the columns of exportColumns, with
+// runtimes in the format chosen for the request and poster URLs filled in.
+func (app *application) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	format := app.readString(qs, "format", "ndjson")
+	criteria := app.readMovieCriteria(qs, v)
+	filters := data.Filters{
+		Sort:         app.readString(qs, "sort", "id"),
+		SortSafeList: movieSortSafeList,
+	}
+
+	if err := app.resolveCriteriaGenres(v, &criteria); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v.Check(validator.In(format, "ndjson", "csv"), "format", "must be ndjson or csv")
+	data.ValidateSort(v, filters)
+	if data.ValidateMovieCriteria(v, criteria); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Without a deadline an export may run for longer than the server's WriteTimeout.
+	if err := clearWriteDeadline(w); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.logError(r, err)
+	}
+
+	// Runtimes are written in the format chosen for the request as they are by the other movie endpoints.
+	w.Header().Add("Vary", "Accept")
+	runtimeFormat := app.contextGetRuntimeFormat(r)
+
+	out := &exportWriter{w: w}
+
+	var (
+		write func(*exportMovie) error
+		flush func() error
+	)
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(out)
+		cw.Write(exportColumns)
+		write = func(movie *exportMovie) error {
+			return cw.Write(movie.record())
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	default:
+		bw := bufio.NewWriter(out)
+		enc := json.NewEncoder(bw)
+		write = func(movie *exportMovie) error {
+			return enc.Encode(movie)
+		}
+		flush = bw.Flush
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="movies.`+format+`"`)
+
+	flusher, _ := w.(http.Flusher)
+	rows := 0
+
+	err := app.models.Movies.Export(r.Context(), criteria, filters, func(movie *data.Movie) error {
+		app.posterURLs(movie)
+		row, err := newExportMovie(movie, runtimeFormat)
+		if err != nil {
+			return err
+		}
+		if err := write(row); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		// An error before anything has reached the client can still be reported normally.
+		if !out.started {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// Part of the export has been sent. Aborting drops the connection so the client cannot mistake a truncated
+		// export for a complete one.
+		app.logError(r, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportColumns names the columns of a CSV export, which are also the members of each NDJSON object.
+var exportColumns = []string{
+	"id", "created_at", "title", "year", "runtime", "genres", "external_ids", "rating", "rating_count", "posters",
+	"version",
+}
+
+// exportMovie is a movie as it is exported. Both formats carry the same columns, exportColumns, each always present.
+type exportMovie struct {
+	ID          int64             `json:"id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Title       string            `json:"title"`
+	Year        int32             `json:"year"`
+	Runtime     json.RawMessage   `json:"runtime"`
+	Genres      []string          `json:"genres"`
+	ExternalIDs data.ExternalIDs  `json:"external_ids"`
+	Rating      float32           `json:"rating"`
+	RatingCount int32             `json:"rating_count"`
+	Posters     map[string]string `json:"posters"`
+	Version     int32             `json:"version"`
+
+	runtimeText string
+}
+
+// newExportMovie returns the export of movie, with its runtime written in format. The poster URLs must already have
+// been filled in.
+func newExportMovie(movie *data.Movie, format data.RuntimeFormat) (*exportMovie, error) {
+	runtime, err := movie.Runtime.MarshalFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &exportMovie{
+		ID:          movie.ID,
+		CreatedAt:   movie.CreatedAt,
+		Title:       movie.Title,
+		Year:        movie.Year,
+		Runtime:     runtime,
+		Genres:      movie.Genres,
+		ExternalIDs: movie.ExternalIDs,
+		Rating:      movie.Rating,
+		RatingCount: movie.RatingCount,
+		Posters:     movie.Posters,
+		Version:     movie.Version,
+		runtimeText: movie.Runtime.Text(format),
+	}
+	if e.Genres == nil {
+		e.Genres = []string{}
+	}
+	if e.ExternalIDs == nil {
+		e.ExternalIDs = data.ExternalIDs{}
+	}
+	if e.Posters == nil {
+		e.Posters = map[string]string{}
+	}
+	return e, nil
+}
+
+// record returns the CSV row of the movie, in the order of exportColumns. Lists are separated by ";" and maps are
+// written as key:value pairs.
+func (e *exportMovie) record() []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		e.CreatedAt.Format(time.RFC3339),
+		e.Title,
+		strconv.Itoa(int(e.Year)),
+		e.runtimeText,
+		strings.Join(e.Genres, ";"),
+		formatPairs(e.ExternalIDs),
+		strconv.FormatFloat(float64(e.Rating), 'f', -1, 32),
+		strconv.Itoa(int(e.RatingCount)),
+		formatPairs(e.Posters),
+		strconv.Itoa(int(e.Version)),
+	}
+}
+
+// formatPairs writes a map for a CSV export as key:value pairs separated by ";" in order of key, such as the external
+// IDs "imdb:tt3521164;tmdb:277834".
+func formatPairs(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+":"+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+// exportWriter records whether any of an export has been written to the client, after which the status can no longer
+// be changed.
+type exportWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	e.started = true
+	return e.w.Write(p)
+}
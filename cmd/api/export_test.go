@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"movieDB/internal/data"
+	"net/http"
+	"testing"
+)
+
+func TestExportMoviesCSV(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write", "movies:export")
+	ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation","adventure"],"external_ids":{"tmdb":"277834","imdb":"tt3521164"}}`)
+	ts.createMovie(t, `{"title":"Up","year":2009,"runtime":"96 mins","genres":["animation"]}`)
+
+	res := ts.request(t, http.MethodGet, "/v1/movies/export?format=csv", "")
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	records, err := csv.NewReader(res.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records; want a header and 2 rows", len(records))
+	}
+
+	column := -1
+	for i, name := range records[0] {
+		if name == "external_ids" {
+			column = i
+		}
+	}
+	if column < 0 {
+		t.Fatalf("got header %v; want an external_ids column", records[0])
+	}
+
+	for i, want := range []string{"imdb:tt3521164;tmdb:277834", ""} {
+		if got := records[i+1][column]; got != want {
+			t.Errorf("row %d: got external_ids %q; want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestExportMoviesFormatsMatch(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write", "movies:export")
+	id := ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+	if _, err := ts.app.models.Movies.SetPoster(id, "poster.jpg"); err != nil {
+		t.Fatal(err)
+	}
+
+	res := ts.request(t, http.MethodGet, "/v1/movies/export?format=ndjson&runtime_format=hm", "")
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	if !scanner.Scan() {
+		t.Fatal("got no NDJSON rows")
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(scanner.Bytes(), &object); err != nil {
+		t.Fatal(err)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/movies/export?format=csv&runtime_format=hm", "")
+	defer res.Body.Close()
+	records, err := csv.NewReader(res.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records; want a header and a row", len(records))
+	}
+
+	header, row := records[0], records[1]
+	if len(object) != len(header) {
+		t.Errorf("got NDJSON members %v; want the CSV columns %v", object, header)
+	}
+	for i, column := range header {
+		if _, ok := object[column]; !ok {
+			t.Errorf("got no NDJSON member for CSV column %s", column)
+		}
+
+		switch column {
+		case "runtime":
+			if row[i] != "1h 47m" || object[column] != "1h 47m" {
+				t.Errorf("got runtimes %q and %v; want 1h 47m in both", row[i], object[column])
+			}
+		case "posters":
+			want := ts.app.blobs.URL(data.PosterKey(id, "poster.jpg", data.PosterOriginal))
+			posters, _ := object[column].(map[string]interface{})
+			if row[i] != data.PosterOriginal+":"+want || posters[data.PosterOriginal] != want {
+				t.Errorf("got posters %q and %v; want the %s URL %s in both", row[i], posters, data.PosterOriginal, want)
+			}
+		case "created_at":
+			if row[i] == "" || object[column] == nil {
+				t.Errorf("got created_at %q and %v; want it in both", row[i], object[column])
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/url"
+)
+
+// fieldset holds the fields a client limited a movie response to, all when empty, along with the related resources
+// it asked to embed in each movie.
+type fieldset struct {
+	fields   []string
+	includes []string
+}
+
+// readFieldset reads the fields and include parameters from the query string, as comma separated lists of
+// data.MovieFields and data.MovieIncludes.
+func (app *application) readFieldset(qs url.Values, v *validator.Validator) fieldset {
+	fs := fieldset{
+		fields:   app.readCSV(qs, "fields", []string{}),
+		includes: app.readCSV(qs, "include", []string{}),
+	}
+
+	data.ValidateFields(v, fs.fields)
+	data.ValidateIncludes(v, fs.includes)
+
+	return fs
+}
+
+// applyFieldset limits each movie to the fields of fs and embeds the resources it includes. Each included resource
+// is read for every movie with a single query, movies with none of a resource are written without it.
+func (app *application) applyFieldset(fs fieldset, movies ...*data.Movie) error {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		movie.Fields = fs.fields
+		ids[i] = movie.ID
+	}
+
+	if len(movies) == 0 {
+		return nil
+	}
+
+	for _, include := range fs.includes {
+		switch include {
+		case "credits":
+			credits, err := app.models.Credits.GetAllForMovies(ids)
+			if err != nil {
+				return err
+			}
+			for _, movie := range movies {
+				movie.Credits = credits[movie.ID]
+			}
+		case "ratings":
+			ratings, err := app.models.Ratings.GetLatestForMovies(ids, data.IncludedRatings)
+			if err != nil {
+				return err
+			}
+			for _, movie := range movies {
+				movie.Ratings = ratings[movie.ID]
+			}
+		case "titles":
+			titles, err := app.models.Titles.GetAllForMovies(ids)
+			if err != nil {
+				return err
+			}
+			for _, movie := range movies {
+				movie.Titles = titles[movie.ID]
+			}
+		}
+	}
+
+	return nil
+}
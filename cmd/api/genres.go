@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+	"strings"
+)
+
+// listGenresHandler lists the whole genre taxonomy ordered by slug.
+func (app *application) listGenresHandler(w http.ResponseWriter, r *http.Request) {
+	genres, err := app.models.Genres.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"genres": genres}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showGenreHandler shows a single genre.
+func (app *application) showGenreHandler(w http.ResponseWriter, r *http.Request) {
+	genre, ok := app.readGenreParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"genre": genre}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createGenreHandler adds a genre to the taxonomy. The slug and aliases are normalised with data.NormalizeGenre.
+func (app *application) createGenreHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Slug    string   `json:"slug"`
+		Name    string   `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	genre := &data.Genre{
+		Slug:    data.NormalizeGenre(input.Slug),
+		Name:    input.Name,
+		Aliases: normalizeGenres(input.Aliases),
+	}
+
+	v := validator.New()
+
+	if data.ValidateGenre(v, genre); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Genres.Insert(genre)
+	if err != nil {
+		app.genreErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"genre": genre}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateGenreHandler changes a genre. Aliases, when given, replace the existing ones. Changing the slug moves every
+// movie to the new slug and keeps the old one as an alias.
+func (app *application) updateGenreHandler(w http.ResponseWriter, r *http.Request) {
+	genre, ok := app.readGenreParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Slug    *string   `json:"slug"`
+		Name    *string   `json:"name"`
+		Aliases *[]string `json:"aliases"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Slug != nil {
+		genre.Slug = data.NormalizeGenre(*input.Slug)
+	}
+
+	if input.Name != nil {
+		genre.Name = *input.Name
+	}
+
+	if input.Aliases != nil {
+		genre.Aliases = normalizeGenres(*input.Aliases)
+	}
+
+	v := validator.New()
+
+	if data.ValidateGenre(v, genre); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Genres.Update(genre, app.revisionUserID(r))
+	if err != nil {
+		app.genreErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"genre": genre}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteGenreHandler removes a genre from the taxonomy. Genres still carried by movies, including those in the trash,
+// cannot be deleted.
+func (app *application) deleteGenreHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Genres.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.genreErrorResponse(w, r, validator.New(), err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "genre successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readGenreParam reads the genre named by the id parameter, sending a 404 if it does not exist.
+func (app *application) readGenreParam(w http.ResponseWriter, r *http.Request) (*data.Genre, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	genre, err := app.models.Genres.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return genre, true
+}
+
+// genreErrorResponse sends the response for an error returned when saving or deleting a genre.
+func (app *application) genreErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrEditConflict):
+		app.editConflictResponse(w, r)
+	case errors.Is(err, data.ErrDuplicateGenre):
+		v.AddError("slug", "the slug or an alias is already used by another genre")
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrTooManyAliases):
+		v.AddError("aliases", fmt.Sprintf("must leave room for the old slug, which is kept as an alias, within the %d allowed", data.MaxGenreAliases))
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrGenreInUse):
+		v.AddError("genre", "is still used by movies")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// genreIndex loads the taxonomy used to resolve the genres given by clients.
+func (app *application) genreIndex() (data.GenreIndex, error) {
+	genres, err := app.models.Genres.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return data.NewGenreIndex(genres), nil
+}
+
+// resolveMovieGenres replaces the genres of movie with their canonical slugs, recording a validation error if any
+// are not in the taxonomy.
+func (app *application) resolveMovieGenres(v *validator.Validator, movie *data.Movie) error {
+	index, err := app.genreIndex()
+	if err != nil {
+		return err
+	}
+
+	movie.Genres = resolveGenres(v, index, "genres", movie.Genres)
+	return nil
+}
+
+// resolveCriteriaGenres replaces the genres of the listing filters with their canonical slugs, recording a
+// validation error if any are not in the taxonomy.
+func (app *application) resolveCriteriaGenres(v *validator.Validator, criteria *data.MovieCriteria) error {
+	if len(criteria.Genres) == 0 && len(criteria.GenresNot) == 0 {
+		return nil
+	}
+
+	index, err := app.genreIndex()
+	if err != nil {
+		return err
+	}
+
+	criteria.Genres = resolveGenres(v, index, "genres", criteria.Genres)
+	criteria.GenresNot = resolveGenres(v, index, "genres_not", criteria.GenresNot)
+	return nil
+}
+
+// resolveGenres returns the canonical slugs of genres, adding an error under key to v for any which match no genre.
+// A nil slice is returned unchanged so that ValidateMovie still reports missing genres.
+func resolveGenres(v *validator.Validator, index data.GenreIndex, key string, genres []string) []string {
+	if genres == nil {
+		return nil
+	}
+
+	slugs, unknown := index.Resolve(genres)
+	if len(unknown) > 0 {
+		v.AddError(key, "must not contain unknown genres: "+strings.Join(unknown, ", "))
+	}
+	return slugs
+}
+
+// normalizeGenres applies data.NormalizeGenre to each of genres.
+func normalizeGenres(genres []string) []string {
+	normalized := make([]string, len(genres))
+	for i, genre := range genres {
+		normalized[i] = data.NormalizeGenre(genre)
+	}
+	return normalized
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"io"
+	"math"
 	"movieDB/internal/validator"
 	"net/http"
 	"net/url"
@@ -17,11 +18,16 @@ type envelope map[string]interface{}
 
 //readIDParam reads a parameter "ID" from the given context e.g. "/api/users/1/ => 1" and returns an int64.
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readIDParamNamed(r, "id")
+}
+
+// readIDParamNamed reads an ID from the named parameter, for routes holding more than one ID.
+func (app *application) readIDParamNamed(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 
 	return id, nil
@@ -58,33 +64,10 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 	err := dec.Decode(dst)
 
 	if err != nil {
-		var syntaxError *json.SyntaxError
-		var unmarshalTypeError *json.UnmarshalTypeError
-		var invalidUnmarshalError *json.InvalidUnmarshalError
-
-		switch {
-		case errors.As(err, &syntaxError):
-			return fmt.Errorf("body contatains badly formatted json (at character %d)", syntaxError.Offset)
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("body contains badly formatted JSON")
-		case errors.As(err, &unmarshalTypeError):
-			if unmarshalTypeError.Field != "" {
-				return fmt.Errorf("body contains incorrect json type for field %q", unmarshalTypeError.Field)
-			}
-			return fmt.Errorf("body contains incorrectJSON type at %d", unmarshalTypeError.Offset)
-		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
-		case err.Error() == "http: request body too large":
+		if err.Error() == "http: request body too large" {
 			return fmt.Errorf("body must not be larger than %d bytes", maxBytes)
-		case errors.As(err, &invalidUnmarshalError):
-			panic(err)
-		default:
-			return err
 		}
-
+		return jsonError(err)
 	}
 
 	// account for more than object supplied
@@ -95,6 +78,34 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 	return nil
 }
 
+// jsonError translates an error from decoding a JSON value into a message fit for the client.
+func jsonError(err error) error {
+	var syntaxError *json.SyntaxError
+	var unmarshalTypeError *json.UnmarshalTypeError
+	var invalidUnmarshalError *json.InvalidUnmarshalError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf("body contatains badly formatted json (at character %d)", syntaxError.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body contains badly formatted JSON")
+	case errors.As(err, &unmarshalTypeError):
+		if unmarshalTypeError.Field != "" {
+			return fmt.Errorf("body contains incorrect json type for field %q", unmarshalTypeError.Field)
+		}
+		return fmt.Errorf("body contains incorrectJSON type at %d", unmarshalTypeError.Offset)
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Errorf("body contains unknown key %s", fieldName)
+	case errors.As(err, &invalidUnmarshalError):
+		panic(err)
+	default:
+		return err
+	}
+}
+
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
 	s := qs.Get(key)
 	if s == "" {
@@ -130,6 +141,41 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 
 }
 
+// readInt32 reads an integer from the query like readInt, for values held in an int32. One which does not fit is
+// reported rather than left to wrap around on conversion.
+func (app *application) readInt32(qs url.Values, key string, defaultValue int32, v *validator.Validator) int32 {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.ParseInt(s, 10, 32)
+	if errors.Is(err, strconv.ErrRange) {
+		v.AddError(key, fmt.Sprintf("must be between %d and %d", math.MinInt32, math.MaxInt32))
+		return defaultValue
+	}
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+	return int32(i)
+}
+
+//readBool is a small helper to read a boolean from the query
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
 // background is construct of graceful shutdown. It calls the anonymous function before dealing with recover.
 // This may or may not give the function sufficient time to complete.
 func (app *application) background(fn func()) {
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+)
+
+const (
+	// importBatchSize is the number of movies saved per transaction by importMoviesHandler.
+	importBatchSize = 500
+	// importMaxRecordBytes caps a single NDJSON line or array element, matching the limit readJSON places on a whole
+	// request body.
+	importMaxRecordBytes = 1_048_576
+)
+
+// importError reports why a single record of an import was rejected.
+type importError struct {
+	Line   int               `json:"line"`
+	Errors map[string]string `json:"errors"`
+}
+
+// importReport summarises an import for the client.
+type importReport struct {
+	DryRun   bool          `json:"dry_run"`
+	Received int           `json:"received"`
+	Valid    int           `json:"valid"`
+	Imported int           `json:"imported"`
+	Errors   []importError `json:"errors"`
+}
+
+// importMoviesHandler creates movies in bulk from either a JSON array or newline-delimited JSON (NDJSON), each record
+// taking the same fields as createMovieHandler. The body is read as a stream rather than through readJSON, so it is
+// not subject to the 1MB limit, although each record is. Valid records are saved in batches of importBatchSize and
+// invalid ones are reported by line; for an array the line is the position of the element. With dry_run=true records
+// are validated but nothing is saved.
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	dryRun := app.readBool(r.URL.Query(), "dry_run", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Without deadlines an import may take longer to upload and save than the server's ReadTimeout and WriteTimeout.
+	if err := clearReadDeadline(w); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.logError(r, err)
+	}
+	if err := clearWriteDeadline(w); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.logError(r, err)
+	}
+
+	// The taxonomy is loaded once rather than for every record.
+	index, err := app.genreIndex()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	report := importReport{DryRun: dryRun, Errors: []importError{}}
+	batch := make([]*data.Movie, 0, importBatchSize)
+	lines := make([]int, 0, importBatchSize)
+
+	flush := func() {
+		if dryRun {
+			batch, lines = batch[:0], lines[:0]
+			return
+		}
+
+		// The batch is saved in one transaction, so when a movie is rejected it is reported and the others are tried
+		// again without it.
+		for len(batch) > 0 {
+			err := app.models.Movies.InsertBatch(batch, app.revisionUserID(r))
+
+			var batchErr *data.BatchError
+			switch {
+			case err == nil:
+				report.Imported += len(batch)
+			case errors.As(err, &batchErr):
+				i := batchErr.Index
+				report.Errors = append(report.Errors, app.importSaveError(r, lines[i], batchErr.Err))
+				batch = append(batch[:i], batch[i+1:]...)
+				lines = append(lines[:i], lines[i+1:]...)
+				continue
+			default:
+				app.logError(r, err)
+				for _, line := range lines {
+					report.Errors = append(report.Errors, importError{
+						Line:   line,
+						Errors: map[string]string{"movie": "could not be saved"},
+					})
+				}
+			}
+			break
+		}
+
+		batch, lines = make([]*data.Movie, 0, importBatchSize), make([]int, 0, importBatchSize)
+	}
+
+	lastLine := 0
+	err = readImportRecords(r.Body, func(line int, record []byte) {
+		report.Received++
+		lastLine = line
+
+		movie, err := decodeImportRecord(record)
+		if err != nil {
+			report.Errors = append(report.Errors, importError{Line: line, Errors: map[string]string{"json": err.Error()}})
+			return
+		}
+
+		v := validator.New()
+		movie.Genres = resolveGenres(v, index, "genres", movie.Genres)
+		if data.ValidateMovie(v, movie); !v.Valid() {
+			report.Errors = append(report.Errors, importError{Line: line, Errors: v.Errors})
+			return
+		}
+		report.Valid++
+
+		batch = append(batch, movie)
+		lines = append(lines, line)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	})
+	flush()
+
+	if err != nil {
+		if report.Received == 0 {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		// Records read before the body became unreadable have been saved, so the report is still sent.
+		report.Errors = append(report.Errors, importError{Line: lastLine + 1, Errors: map[string]string{"body": err.Error()}})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// importSaveError reports why the movie read from line could not be saved.
+func (app *application) importSaveError(r *http.Request, line int, err error) importError {
+	if errors.Is(err, data.ErrDuplicateExternalID) {
+		return importError{
+			Line:   line,
+			Errors: map[string]string{"external_ids": "must not contain an ID already used by another movie"},
+		}
+	}
+
+	app.logError(r, err)
+	return importError{Line: line, Errors: map[string]string{"movie": "could not be saved"}}
+}
+
+// decodeImportRecord decodes a single movie, rejecting unknown fields as readJSON does.
+func decodeImportRecord(record []byte) (*data.Movie, error) {
+	var input struct {
+		Title       string           `json:"title"`
+		Year        int32            `json:"year"`
+		Runtime     data.Runtime     `json:"runtime"`
+		Genres      []string         `json:"genres"`
+		ExternalIDs data.ExternalIDs `json:"external_ids"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(record))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&input); err != nil {
+		return nil, jsonError(err)
+	}
+	if dec.More() {
+		return nil, errors.New("line must only contain a single json value")
+	}
+
+	return &data.Movie{
+		Title:       input.Title,
+		Year:        input.Year,
+		Runtime:     input.Runtime,
+		Genres:      input.Genres,
+		ExternalIDs: input.ExternalIDs,
+	}, nil
+}
+
+// readImportRecords calls fn with each record of body in turn. A body whose first character is [ is read as a JSON
+// array, anything else as NDJSON where blank lines are skipped. Reading stops at the first error, which is returned.
+func readImportRecords(body io.Reader, fn func(line int, record []byte)) error {
+	br := bufio.NewReader(body)
+
+	first, err := peekNonSpace(br)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("body must not be empty")
+		}
+		return err
+	}
+
+	if first == '[' {
+		return readImportArray(br, fn)
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 64*1024), importMaxRecordBytes)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		record := bytes.TrimSpace(scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+		fn(line, record)
+	}
+
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("line must not be larger than %d bytes", importMaxRecordBytes)
+	}
+	return scanner.Err()
+}
+
+// readImportArray reads the elements of a JSON array one at a time, so the whole array is never held in memory.
+func readImportArray(body io.Reader, fn func(line int, record []byte)) error {
+	limited := &recordLimitReader{r: body, limit: importMaxRecordBytes}
+	dec := json.NewDecoder(limited)
+
+	// Consume the opening [.
+	if _, err := dec.Token(); err != nil {
+		return jsonError(err)
+	}
+
+	line := 0
+	for dec.More() {
+		line++
+
+		// Bytes the decoder has already buffered count towards the element, so no more than twice the limit is read
+		// ahead before a large element is rejected.
+		limited.limit = limited.read + importMaxRecordBytes
+
+		var record json.RawMessage
+		if err := dec.Decode(&record); err != nil {
+			return jsonError(err)
+		}
+		if len(record) > importMaxRecordBytes {
+			return errImportRecordTooLarge
+		}
+		fn(line, record)
+	}
+
+	// Consume the closing ].
+	if _, err := dec.Token(); err != nil {
+		return jsonError(err)
+	}
+
+	return nil
+}
+
+var errImportRecordTooLarge = fmt.Errorf("element must not be larger than %d bytes", importMaxRecordBytes)
+
+// recordLimitReader fails once more than limit bytes have been read in total, which readImportArray moves on as each
+// element is decoded.
+type recordLimitReader struct {
+	r     io.Reader
+	read  int64
+	limit int64
+}
+
+func (l *recordLimitReader) Read(p []byte) (int, error) {
+	if l.read >= l.limit {
+		return 0, errImportRecordTooLarge
+	}
+	if int64(len(p)) > l.limit-l.read {
+		p = p[:l.limit-l.read]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"movieDB/internal/data"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadImportRecords(t *testing.T) {
+	large := `{"title":"` + strings.Repeat("a", importMaxRecordBytes) + `"}`
+	medium := `{"title":"` + strings.Repeat("a", importMaxRecordBytes/2) + `"}`
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []int // lines passed to fn
+		wantErr bool
+	}{
+		{"ndjson", "{\"a\":1}\n\n{\"a\":2}\n", []int{1, 3}, false},
+		{"array", ` [{"a":1}, {"a":2}]`, []int{1, 2}, false},
+		{"empty", "  \n", nil, true},
+		{"unterminated array", `[{"a":1},`, []int{1}, true},
+		{"large line", "{\"a\":1}\n" + large + "\n", []int{1}, true},
+		{"large element", `[{"a":1},` + large + `]`, []int{1}, true},
+		{"many medium elements", `[` + strings.Repeat(medium+`,`, 4) + medium + `]`, []int{1, 2, 3, 4, 5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			err := readImportRecords(strings.NewReader(tt.body), func(line int, record []byte) {
+				got = append(got, line)
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v; want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got lines %v; want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got lines %v; want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// failingBatches fails every InsertBatch as a lost connection would, counting the attempts.
+type failingBatches struct {
+	data.MovieStore
+	attempts int
+}
+
+func (f *failingBatches) InsertBatch(movies []*data.Movie, userID *int64) error {
+	f.attempts++
+	return errors.New("connection reset by peer")
+}
+
+func TestImportMovies(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+
+	body := `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}
+{"title":"","year":2016,"runtime":"107 mins","genres":["animation"]}
+{"title":"Up","year":2009,"runtime":"96 mins","genres":["animation"],"rating":5}
+`
+
+	var env struct {
+		Import importReport `json:"import"`
+	}
+	ts.requestJSON(t, http.MethodPost, "/v1/movies/import", body, http.StatusOK, &env)
+
+	report := env.Import
+	if report.Received != 3 || report.Valid != 1 || report.Imported != 1 {
+		t.Errorf("got received %d, valid %d, imported %d; want 3, 1, 1", report.Received, report.Valid, report.Imported)
+	}
+	if len(report.Errors) != 2 || report.Errors[0].Line != 2 || report.Errors[1].Line != 3 {
+		t.Fatalf("got errors %+v; want errors for lines 2 and 3", report.Errors)
+	}
+	if _, ok := report.Errors[0].Errors["title"]; !ok {
+		t.Errorf("got errors %v for line 2; want a title error", report.Errors[0].Errors)
+	}
+}
+
+func TestImportMoviesExternalIDs(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"],"external_ids":{"imdb":"tt3521164"}}`)
+
+	// The second record duplicates the existing movie's ID and the fourth the third's, the last has a malformed ID.
+	body := `[
+		{"title":"Up","year":2009,"runtime":"96 mins","genres":["animation"],"external_ids":{"imdb":"tt1049413"}},
+		{"title":"Vaiana","year":2016,"runtime":"107 mins","genres":["animation"],"external_ids":{"imdb":"tt3521164"}},
+		{"title":"Coco","year":2017,"runtime":"105 mins","genres":["animation"],"external_ids":{"tmdb":"354912"}},
+		{"title":"Coco","year":2017,"runtime":"105 mins","genres":["animation"],"external_ids":{"tmdb":"354912"}},
+		{"title":"Soul","year":2020,"runtime":"100 mins","genres":["animation"],"external_ids":{"imdb":"2948372"}}
+	]`
+
+	var env struct {
+		Import importReport `json:"import"`
+	}
+	ts.requestJSON(t, http.MethodPost, "/v1/movies/import", body, http.StatusOK, &env)
+
+	report := env.Import
+	if report.Valid != 4 || report.Imported != 2 {
+		t.Errorf("got valid %d, imported %d; want 4, 2", report.Valid, report.Imported)
+	}
+
+	want := map[int]bool{2: true, 4: true, 5: true}
+	for _, e := range report.Errors {
+		if !want[e.Line] {
+			t.Errorf("got unexpected error for line %d: %v", e.Line, e.Errors)
+			continue
+		}
+		if _, ok := e.Errors["external_ids"]; !ok {
+			t.Errorf("got errors %v for line %d; want an external_ids error", e.Errors, e.Line)
+		}
+		delete(want, e.Line)
+	}
+	if len(want) > 0 {
+		t.Errorf("got no errors for lines %v", want)
+	}
+}
+
+func TestImportMoviesStoreFailure(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	store := &failingBatches{MovieStore: ts.app.models.Movies}
+	ts.app.models.Movies = store
+
+	body := `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}
+{"title":"Up","year":2009,"runtime":"96 mins","genres":["animation"]}
+{"title":"Coco","year":2017,"runtime":"105 mins","genres":["animation"]}
+`
+
+	var env struct {
+		Import importReport `json:"import"`
+	}
+	ts.requestJSON(t, http.MethodPost, "/v1/movies/import", body, http.StatusOK, &env)
+
+	// An error which is not about one record fails the whole batch once, rather than being retried record by record.
+	if store.attempts != 1 {
+		t.Errorf("got %d attempts to save the batch; want 1", store.attempts)
+	}
+	report := env.Import
+	if report.Imported != 0 || len(report.Errors) != 3 {
+		t.Fatalf("got imported %d with errors %+v; want none imported and an error per line", report.Imported, report.Errors)
+	}
+	for i, e := range report.Errors {
+		if e.Line != i+1 || e.Errors["movie"] != "could not be saved" {
+			t.Errorf("got error %+v; want line %d could not be saved", e, i+1)
+		}
+	}
+}
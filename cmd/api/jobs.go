@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"movieDB/internal/data"
+	"strconv"
+	"time"
+)
+
+// schedule runs fn every interval in the background until the server begins shutting down. Errors and panics are
+// logged rather than stopping the schedule, the next tick simply tries again.
+func (app *application) schedule(name string, interval time.Duration, fn func() error) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		run := func() {
+			defer func() {
+				if err := recover(); err != nil {
+					app.logger.PrintError(fmt.Errorf("%s", err), map[string]string{"job": name})
+				}
+			}()
+
+			if err := fn(); err != nil {
+				app.logger.PrintError(err, map[string]string{"job": name})
+			}
+		}
+
+		for {
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
+
+// purgeTrash permanently deletes movies which have been in the trash for longer than the retention period, along
+// with their posters. Posters are kept while a movie is in the trash so that restoring it brings them back.
+func (app *application) purgeTrash() error {
+	purged, err := app.models.Movies.PurgeDeleted(time.Now().Add(-app.config.trash.retention))
+	if err != nil {
+		return err
+	}
+
+	for _, id := range purged {
+		err := app.blobs.DeletePrefix(context.Background(), data.PosterPrefix(id))
+		if err != nil {
+			// The movie is already gone, so report the orphaned files and carry on with the rest.
+			app.logger.PrintError(err, map[string]string{"movie_id": strconv.FormatInt(id, 10)})
+		}
+	}
+
+	if len(purged) > 0 {
+		app.logger.PrintInfo("purged deleted movies", map[string]string{
+			"count": strconv.Itoa(len(purged)),
+		})
+	}
+	return nil
+}
+
+// reapUnactivatedUsers deletes the accounts which were never activated within the maximum age, see
+// data.UserModel.DeleteUnactivated.
+func (app *application) reapUnactivatedUsers() error {
+	deleted, err := app.models.Users.DeleteUnactivated(time.Now().Add(-app.config.activation.maxAge))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		app.logger.PrintInfo("deleted unactivated users", map[string]string{
+			"count": strconv.FormatInt(deleted, 10),
+		})
+	}
+	return nil
+}
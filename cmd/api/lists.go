@@ -0,0 +1,449 @@
+package main
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+)
+
+// listListsHandler lists the authenticated user's lists, without their entries.
+func (app *application) listListsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "id"),
+		SortSafeList: []string{"id", "name"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	lists, metadata, err := app.models.Lists.GetAllForUser(app.contextGetUser(r).ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"lists": lists, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createListHandler creates an empty, private list for the authenticated user.
+func (app *application) createListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list := &data.List{
+		UserID: app.contextGetUser(r).ID,
+		Name:   input.Name,
+	}
+
+	v := validator.New()
+
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Insert(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateListName):
+			v.AddError("name", "you already have a list with this name")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showListHandler shows one of the authenticated user's lists along with its entries.
+func (app *application) showListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeListWithEntries(w, r, list)
+}
+
+// showSharedListHandler shows a shared list to anyone holding its share token, without requiring authentication.
+func (app *application) showSharedListHandler(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	list, err := app.models.Lists.GetForShareToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Those holding the share link are not told who owns the list.
+	list.UserID = 0
+
+	app.writeListWithEntries(w, r, list)
+}
+
+// updateListHandler renames one of the authenticated user's lists.
+func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		list.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Update(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateListName):
+			v.AddError("name", "you already have a list with this name")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteListHandler removes one of the authenticated user's lists along with its entries.
+func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Lists.Delete(list.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "list successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// shareListHandler gives a list a new share token, which is returned once in the response and is needed to read the
+// list through showSharedListHandler. Sharing an already shared list replaces its token, revoking the old link.
+func (app *application) shareListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := list.Share()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !app.saveListSharing(w, r, list) {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list, "share_token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unshareListHandler makes a list private again, revoking its share link.
+func (app *application) unshareListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	list.Unshare()
+
+	if !app.saveListSharing(w, r, list) {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// addListEntryHandler appends a movie to the end of one of the authenticated user's lists.
+func (app *application) addListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		MovieID int64  `json:"movie_id"`
+		Notes   string `json:"notes"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := &data.ListEntry{
+		ListID: list.ID,
+		Movie:  &data.Movie{ID: input.MovieID},
+		Notes:  input.Notes,
+	}
+
+	v := validator.New()
+
+	if data.ValidateListEntry(v, entry); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Movies in the trash cannot be added, as they would not be shown on the list.
+	entry.Movie, err = app.models.Movies.Get(input.MovieID)
+	if err == nil {
+		err = app.models.Lists.AddEntry(entry)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("movie_id", "must refer to an existing movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateListEntry):
+			v.AddError("movie_id", "is already on this list")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"entry": entry}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateListEntryHandler changes the notes of an entry and/or moves it to a new 1-based position, shifting the
+// entries in between. The updated list is returned.
+func (app *application) updateListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, movieID, ok := app.readOwnListEntry(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Notes    *string `json:"notes"`
+		Position *int32  `json:"position"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Notes != nil {
+		data.ValidateListEntry(v, &data.ListEntry{Movie: &data.Movie{ID: movieID}, Notes: *input.Notes})
+	}
+	if input.Position != nil {
+		v.Check(*input.Position >= 1, "position", "must be greater than zero")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.Notes != nil {
+		err = app.models.Lists.SetEntryNotes(list.ID, movieID, *input.Notes)
+	}
+	if err == nil && input.Position != nil {
+		err = app.models.Lists.MoveEntry(list.ID, movieID, *input.Position)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeListWithEntries(w, r, list)
+}
+
+// deleteListEntryHandler removes a movie from one of the authenticated user's lists.
+func (app *application) deleteListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, movieID, ok := app.readOwnListEntry(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Lists.RemoveEntry(list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully removed from list"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readOwnList reads the list named by the id parameter, sending a 404 if it does not exist or belongs to another
+// user so that the existence of other users' lists is not revealed.
+func (app *application) readOwnList(w http.ResponseWriter, r *http.Request) (*data.List, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	list, err := app.models.Lists.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if list.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return list, true
+}
+
+// readOwnListEntry reads the list named by the id parameter as readOwnList does, along with the movie_id parameter.
+func (app *application) readOwnListEntry(w http.ResponseWriter, r *http.Request) (*data.List, int64, bool) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+
+	movieID, err := app.readIDParamNamed(r, "movie_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, 0, false
+	}
+
+	return list, movieID, true
+}
+
+// saveListSharing saves a change to the sharing of a list, sending the error response and returning false on failure.
+func (app *application) saveListSharing(w http.ResponseWriter, r *http.Request, list *data.List) bool {
+	err := app.models.Lists.Update(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// writeListWithEntries loads the entries of list and sends it, with the movies presented as the movie endpoints do.
+func (app *application) writeListWithEntries(w http.ResponseWriter, r *http.Request, list *data.List) {
+	entries, err := app.models.Lists.GetEntries(list.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	list.Entries = entries
+
+	movies := make([]*data.Movie, len(entries))
+	for i, entry := range entries {
+		movies[i] = entry.Movie
+	}
+
+	err = app.presentMovies(w, r, fieldset{}, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestShowSharedListLeavesOutOwner(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write", "lists:read", "lists:write")
+	movieID := ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+
+	var created struct {
+		List struct {
+			ID int64 `json:"id"`
+		} `json:"list"`
+	}
+	ts.requestJSON(t, http.MethodPost, "/v1/lists", `{"name":"Watchlist"}`, http.StatusCreated, &created)
+	path := fmt.Sprintf("/v1/lists/%d", created.List.ID)
+
+	ts.requestJSON(t, http.MethodPost, path+"/entries", fmt.Sprintf(`{"movie_id":%d}`, movieID), http.StatusCreated, nil)
+
+	var shared struct {
+		ShareToken string `json:"share_token"`
+	}
+	ts.requestJSON(t, http.MethodPut, path+"/share", "", http.StatusOK, &shared)
+
+	// The owner still sees themselves on their own list.
+	var own struct {
+		List map[string]interface{} `json:"list"`
+	}
+	ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, &own)
+	if _, ok := own.List["user_id"]; !ok {
+		t.Errorf("got list %v; want the owner's user_id", own.List)
+	}
+
+	ts.token = ""
+	var env struct {
+		List map[string]interface{} `json:"list"`
+	}
+	ts.requestJSON(t, http.MethodGet, "/v1/shared/lists/"+shared.ShareToken, "", http.StatusOK, &env)
+
+	if _, ok := env.List["user_id"]; ok {
+		t.Errorf("got shared list %v; want no user_id", env.List)
+	}
+	if entries, _ := env.List["entries"].([]interface{}); len(entries) != 1 {
+		t.Errorf("got entries %v; want the movie", env.List["entries"])
+	}
+}
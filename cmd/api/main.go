@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"flag"
+	"fmt"
 	_ "github.com/lib/pq"
+	"movieDB/internal/blob"
 	"movieDB/internal/data"
 	"movieDB/internal/jsonlog"
 	"movieDB/internal/mailer"
+	"movieDB/internal/validator"
 	"os"
 	"sync"
 	"time"
@@ -16,9 +19,10 @@ import (
 const version = "1.0.0"
 
 type config struct {
-	port int
-	env  string
-	db   struct {
+	port    int
+	env     string
+	storage string
+	db      struct {
 		dsn         string
 		maxOpenConn int
 		maxIdleConn int
@@ -29,6 +33,28 @@ type config struct {
 		burst   int
 		enabled bool
 	}
+	search struct {
+		config string
+	}
+	titles struct {
+		language string
+	}
+	etag struct {
+		strict bool
+	}
+	blob struct {
+		dir string
+		url string
+	}
+	trash struct {
+		retention     time.Duration
+		purgeInterval time.Duration
+	}
+	activation struct {
+		resendInterval time.Duration
+		maxAge         time.Duration
+		reapInterval   time.Duration
+	}
 	smtp struct {
 		host     string
 		port     int
@@ -39,17 +65,22 @@ type config struct {
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config   config
+	logger   *jsonlog.Logger
+	models   data.Models
+	mailer   mailer.Mailer
+	blobs    blob.Store // posters and their thumbnails
+	wg       sync.WaitGroup
+	shutdown chan struct{} // closed when the server begins shutting down, stopping scheduled jobs
+
+	activationThrottle *throttle // limits resent activation emails per address
 }
 
 func main() {
 	var cfg config
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production")
+	flag.StringVar(&cfg.storage, "storage", "postgres", "Storage backend (postgres|memory)")
 
 	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("GREENLIGHT_DB_DSN"), "PostgresSQL DSN")
 
@@ -60,6 +91,21 @@ func main() {
 	flag.IntVar(&cfg.limiter.burst, "limited-burst", 4, "rate limiter max burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
 
+	flag.StringVar(&cfg.search.config, "search-config", "simple", "Default text search configuration for title searches")
+	flag.StringVar(&cfg.titles.language, "titles-language", "en", "Language of the default movie titles, see Accept-Language")
+
+	flag.BoolVar(&cfg.etag.strict, "etag-strict", false, "Require an If-Match header on movie updates and deletes")
+
+	flag.StringVar(&cfg.blob.dir, "blob-dir", "uploads", "Directory in which uploaded files such as posters are stored")
+	flag.StringVar(&cfg.blob.url, "blob-url", "/v1/images/", "Base URL from which stored files are served")
+
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted movies are kept before being purged (0 keeps them forever)")
+	flag.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", time.Hour, "How often deleted movies past retention are purged")
+
+	flag.DurationVar(&cfg.activation.resendInterval, "activation-resend-interval", 5*time.Minute, "Minimum time between activation emails resent to the same address")
+	flag.DurationVar(&cfg.activation.maxAge, "activation-max-age", 7*24*time.Hour, "How long unactivated accounts are kept before being deleted (0 keeps them forever)")
+	flag.DurationVar(&cfg.activation.reapInterval, "activation-reap-interval", time.Hour, "How often unactivated accounts past their maximum age are deleted")
+
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "smtp.mailtrap.io", "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP Port")
 	flag.StringVar(&cfg.smtp.username, "smtp-username", "xxx", "SMTP Username")
@@ -68,19 +114,51 @@ func main() {
 
 	flag.Parse()
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
-	db, err := openDB(cfg)
+
+	if !validator.In(cfg.search.config, data.SearchConfigs...) {
+		logger.PrintFatal(fmt.Errorf("unsupported search configuration %q", cfg.search.config), nil)
+	}
+
+	var models data.Models
+	switch cfg.storage {
+	case "memory":
+		// The in-memory backend needs no database, data does not survive a restart.
+		models = data.NewMemoryModels()
+		logger.PrintInfo("using in-memory storage", nil)
+	case "postgres":
+		db, err := openDB(cfg)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+
+		defer db.Close()
+		logger.PrintInfo("database connection pool established", nil)
+		models = data.NewModels(db)
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown storage backend %q", cfg.storage), nil)
+	}
+
+	blobs, err := blob.NewFileStore(cfg.blob.dir, cfg.blob.url)
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
 
-	defer db.Close()
-	logger.PrintInfo("database connection pool established", nil)
-
 	app := application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:   cfg,
+		logger:   logger,
+		models:   models,
+		mailer:   mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		blobs:    blobs,
+		shutdown: make(chan struct{}),
+
+		activationThrottle: newThrottle(cfg.activation.resendInterval),
+	}
+
+	if cfg.trash.retention > 0 {
+		app.schedule("purge trash", cfg.trash.purgeInterval, app.purgeTrash)
+	}
+	if cfg.activation.maxAge > 0 {
+		app.schedule("reap unactivated users", cfg.activation.reapInterval, app.reapUnactivatedUsers)
 	}
 
 	err = app.serve()
@@ -19,6 +19,11 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// http.ErrAbortHandler deliberately cuts off a response which has already started, e.g. a failed
+				// export, so the server is left to close the connection rather than an error being appended.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
 				w.Header().Set("Connection", "close")
 				app.serverErrorResponse(w, r, fmt.Errorf("%s", err))
 			}
@@ -126,7 +131,34 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			}
 			return
 		}
+
+		// The last use of a session is only informational, so failing to record it does not fail the request. It is
+		// only recorded every SessionTouchInterval, which the time read with the user is enough to check.
+		if time.Since(user.TokenLastUsedAt) >= data.SessionTouchInterval {
+			if err := app.models.Tokens.Touch(token); err != nil {
+				app.logError(r, err)
+			}
+		}
+
 		r = app.contextSetUser(r, user)
+		r = app.contextSetToken(r, token)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// negotiateRuntimeFormat records the format movie runtimes are written in for the request, as chosen by
+// readRuntimeFormat. An unknown runtime_format parameter is refused before the request reaches its handler.
+func (app *application) negotiateRuntimeFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format, ok := readRuntimeFormat(r)
+		if !ok {
+			v := validator.New()
+			v.AddError("runtime_format", "must be one of "+runtimeFormatNames())
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		r = app.contextSetRuntimeFormat(r, format)
 		next.ServeHTTP(w, r)
 	})
 }
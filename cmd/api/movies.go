@@ -4,17 +4,30 @@ import (
 	"errors"
 	"fmt"
 	"movieDB/internal/data"
+	"movieDB/internal/jsonpatch"
 	"movieDB/internal/validator"
 	"net/http"
+	"net/url"
 )
 
+// createMovieHandler adds a movie. Unless force=true is passed, a movie from the same year with a near-identical
+// title is taken to be the same film and a 409 listing the candidates is sent instead.
 func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
-		Title   string       `json:"title,omitempty"`
-		Year    int32        `json:"year,omitempty"`
-		Runtime data.Runtime `json:"runtime,omitempty"`
-		Genres  []string     `json:"genres,omitempty"`
+		Title       string           `json:"title,omitempty"`
+		Year        int32            `json:"year,omitempty"`
+		Runtime     data.Runtime     `json:"runtime,omitempty"`
+		Genres      []string         `json:"genres,omitempty"`
+		ExternalIDs data.ExternalIDs `json:"external_ids,omitempty"`
+	}
+
+	v := validator.New()
+
+	force := app.readBool(r.URL.Query(), "force", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -24,20 +37,57 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
-		Genres:  input.Genres,
+		Title:       input.Title,
+		Year:        input.Year,
+		Runtime:     input.Runtime,
+		Genres:      input.Genres,
+		ExternalIDs: input.ExternalIDs,
 	}
 
-	v := validator.New()
+	err = app.resolveMovieGenres(v, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	if data.ValidateMovie(v, movie); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.Insert(movie)
+	if !force {
+		duplicates, err := app.models.Movies.FindDuplicates(movie)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if len(duplicates) > 0 {
+			app.posterURLs(duplicates...)
+			app.formatRuntimes(w, r, duplicates...)
+			app.duplicateMovieResponse(w, r, duplicates)
+			return
+		}
+	}
+
+	err = app.models.Movies.Insert(movie, app.revisionUserID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "must not contain an ID already used by another movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.presentMovies(w, r, fieldset{}, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	etag, err := movieETag(movie)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -45,12 +95,16 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
+	headers.Set("ETag", etag)
 	err = app.writeJSON(w, http.StatusCreated, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// showMovieHandler sends a single movie with an ETag. The tag tracks the representation sent rather than the version,
+// so it also changes when the movie is rated or given a poster, and differs between field sets, languages and runtime
+// formats. A client should send back the tag of its last read in If-Match, not one built from the version.
 func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -58,8 +112,15 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	v := validator.New()
+	fs := app.readFieldset(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	//app.logger.PrintInfo(string(id), nil)
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.models.Movies.Get(id, fs.fields...)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -69,13 +130,33 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+
+	err = app.presentMovies(w, r, fs, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	etag, err := movieETag(movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if app.notModified(w, r, etag) {
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 
 }
 
+// updateMovieHandler applies a partial update, JSON Patch or JSON Merge Patch to a movie. With If-Match the update
+// goes ahead only while the ETag of the movie, as showMovieHandler would send it, is unchanged.
 func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -94,16 +175,44 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// A client which read the movie before sends its ETag, so changes made since are refused rather than overwritten.
+	etag, err := app.currentMovieETag(w, r, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !app.checkIfMatch(w, r, etag) {
+		return
+	}
+
 	var input struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  *[]string     `json:"genres"`
+		Title       *string           `json:"title"`
+		Year        *int32            `json:"year"`
+		Runtime     *data.Runtime     `json:"runtime"`
+		Genres      *[]string         `json:"genres"`
+		ExternalIDs *data.ExternalIDs `json:"external_ids"`
 	}
 
-	err = app.readJSON(w, r, &input)
+	// A JSON Patch or Merge Patch document is applied to the movie's editable fields, which then replace them all
+	// just as a partial movie naming every field would. Any other body is read as a partial movie.
+	if mediaType := patchMediaType(r); mediaType != "" {
+		var snapshot data.MovieSnapshot
+		snapshot, err = app.readMoviePatch(w, r, mediaType, movie)
+		input.Title = &snapshot.Title
+		input.Year = &snapshot.Year
+		input.Runtime = &snapshot.Runtime
+		input.Genres = &snapshot.Genres
+		input.ExternalIDs = &snapshot.ExternalIDs
+	} else {
+		err = app.readJSON(w, r, &input)
+	}
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		switch {
+		case errors.Is(err, jsonpatch.ErrPathNotFound), errors.Is(err, jsonpatch.ErrTestFailed):
+			app.patchConflictResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
 		return
 	}
 
@@ -120,28 +229,56 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		movie.Runtime = *input.Runtime
 	}
 
-	if input.Genres != nil {
-		movie.Genres = *input.Genres
+	// External IDs, when given, replace the existing ones; an empty object removes them all.
+	if input.ExternalIDs != nil {
+		movie.ExternalIDs = *input.ExternalIDs
 	}
 
 	v := validator.New()
 
+	if input.Genres != nil {
+		movie.Genres = *input.Genres
+
+		err = app.resolveMovieGenres(v, movie)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	if data.ValidateMovie(v, movie); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.Update(movie)
+	err = app.models.Movies.Update(movie, data.RevisionUpdate, app.revisionUserID(r))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "must not contain an ID already used by another movie")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	err = app.presentMovies(w, r, fieldset{}, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	etag, err = movieETag(movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -155,13 +292,33 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Movies.Delete(id)
+	movie, err := app.models.Movies.Get(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	etag, err := app.currentMovieETag(w, r, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !app.checkIfMatch(w, r, etag) {
+		return
+	}
+
+	err = app.models.Movies.Delete(movie, app.revisionUserID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
 
 		}
 		return
@@ -175,29 +332,206 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 
 func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieCriteria
 		data.Filters
+		Facets []string
 	}
 	v := validator.New()
 
 	qs := r.URL.Query()
 
+	input.MovieCriteria = app.readMovieCriteria(qs, v)
+	input.Filters = app.readMovieFilters(qs, v)
+	input.Facets = app.readCSV(qs, "facets", []string{})
+	fs := app.readFieldset(qs, v)
+
+	err := app.resolveCriteriaGenres(v, &input.MovieCriteria)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data.ValidateMovieCriteria(v, input.MovieCriteria)
+	data.ValidateFacets(v, input.Facets)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.GetAll(input.MovieCriteria, input.Filters, fs.fields...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if movies == nil {
+		movies = []*data.Movie{}
+	}
+
+	err = app.presentMovies(w, r, fs, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"movies": movies, "metadata": metadata}
+
+	// Facets are opt-in, they are counted over every match rather than the current page.
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Movies.Facets(input.MovieCriteria, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["facets"] = facets
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+
+}
+
+// movieSortSafeList holds the columns the movie listing endpoints may be sorted by. A sort is a comma separated list
+// of these, each descending when prefixed by "-", such as sort=-year,title.
+var movieSortSafeList = []string{"id", "title", "year", "runtime", "relevance", "rating"}
+
+// readMovieCriteria reads the movie search conditions shared by the listing endpoints from the query string.
+func (app *application) readMovieCriteria(qs url.Values, v *validator.Validator) data.MovieCriteria {
 	//p192
 	// QueryString, Key and Default Value
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
-	input.Filters.Page = app.readInt(qs, "page", 1, v)
-	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafeList = []string{"id", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	return data.MovieCriteria{
+		Title:        app.readString(qs, "title", ""),
+		Genres:       app.readCSV(qs, "genres", []string{}),
+		GenresMatch:  app.readString(qs, "genres_match", "all"),
+		GenresNot:    app.readCSV(qs, "genres_not", []string{}),
+		YearMin:      app.readInt32(qs, "year_min", 0, v),
+		YearMax:      app.readInt32(qs, "year_max", 0, v),
+		RuntimeMin:   app.readInt32(qs, "runtime_min", 0, v),
+		RuntimeMax:   app.readInt32(qs, "runtime_max", 0, v),
+		Person:       int64(app.readInt(qs, "person", 0, v)),
+		RatingMin:    app.readInt32(qs, "rating_min", 0, v),
+		SearchConfig: app.readString(qs, "search_config", app.config.search.config),
+	}
+}
 
-	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+// readMovieFilters reads the sort and pagination parameters shared by the listing endpoints from the query string.
+func (app *application) readMovieFilters(qs url.Values, v *validator.Validator) data.Filters {
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "id"),
+		SortSafeList: movieSortSafeList,
+		Cursor:       app.readString(qs, "cursor", ""),
+	}
+
+	// Counting is opt-in when following cursors as it means scanning every match rather than a single page.
+	filters.IncludeTotal = app.readBool(qs, "include_total", filters.Cursor == "", v)
+
+	return filters
+}
+
+// presentMovies prepares movies read for a response to r: titles are localized to Accept-Language, poster URLs
+// filled in and runtimes set to the chosen format, then each movie is limited to the fields of fs and has the
+// resources it includes embedded.
+func (app *application) presentMovies(w http.ResponseWriter, r *http.Request, fs fieldset, movies ...*data.Movie) error {
+	err := app.localizeTitles(w, r, movies...)
+	if err != nil {
+		return err
+	}
+	app.posterURLs(movies...)
+	app.formatRuntimes(w, r, movies...)
+
+	return app.applyFieldset(fs, movies...)
+}
+
+// listTrashedMoviesHandler lists deleted movies which have not yet been purged. It accepts the same parameters as
+// listMoviesHandler.
+func (app *application) listTrashedMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	criteria := app.readMovieCriteria(qs, v)
+	criteria.Deleted = true
+	filters := app.readMovieFilters(qs, v)
+	fs := app.readFieldset(qs, v)
+
+	err := app.resolveCriteriaGenres(v, &criteria)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data.ValidateMovieCriteria(v, criteria)
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.GetAll(criteria, filters, fs.fields...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if movies == nil {
+		movies = []*data.Movie{}
+	}
+
+	err = app.presentMovies(w, r, fs, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// similarSortSafeList holds the columns listSimilarMoviesHandler may be sorted by, most similar first by default.
+var similarSortSafeList = append([]string{"similarity"}, movieSortSafeList...)
+
+// listSimilarMoviesHandler lists the movies most similar to a movie, scored by their genres, release year, runtime,
+// average rating and credited people, see data.SimilarTo. It accepts the same parameters as listMoviesHandler, which
+// narrow the similar movies, and may also be sorted by similarity.
+func (app *application) listSimilarMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	credits, err := app.models.Credits.GetAllForMovie(movie.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	criteria := app.readMovieCriteria(qs, v)
+	criteria.Similar = data.NewSimilarTo(movie, credits)
+	filters := app.readMovieFilters(qs, v)
+	filters.Sort = app.readString(qs, "sort", "similarity")
+	filters.SortSafeList = similarSortSafeList
+	fs := app.readFieldset(qs, v)
+
+	err = app.resolveCriteriaGenres(v, &criteria)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data.ValidateMovieCriteria(v, criteria)
+	if data.ValidateFilters(v, filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	movies, metadata, err := app.models.Movies.GetAll(criteria, filters, fs.fields...)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -207,11 +541,43 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 		movies = []*data.Movie{}
 	}
 
+	err = app.presentMovies(w, r, fs, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
+}
+
+// restoreMovieHandler returns a deleted movie from the trash.
+func (app *application) restoreMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
 
+	movie, err := app.models.Movies.Restore(id, app.revisionUserID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.posterURLs(movie)
+	app.formatRuntimes(w, r, movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 //set BODY '{"title":"Moana","year":2016,"runtime":107,"genres":["animation","adventure"]}'
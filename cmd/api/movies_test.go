@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestListMoviesCriteriaOutOfRange(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+
+	tests := []struct {
+		query string
+		key   string
+		want  string
+	}{
+		// 4294969312 wraps around to 2016 as an int32, which would have matched the movie.
+		{"year_min=4294969312", "year_min", "must be between -2147483648 and 2147483647"},
+		{"year_max=-4294965280", "year_max", "must be between -2147483648 and 2147483647"},
+		{"runtime_min=2147483648", "runtime_min", "must be between -2147483648 and 2147483647"},
+		{"runtime_max=99999999999", "runtime_max", "must be between -2147483648 and 2147483647"},
+		{"rating_min=4294967299", "rating_min", "must be between -2147483648 and 2147483647"},
+		{"year_min=abc", "year_min", "must be an integer value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var env struct {
+				Error map[string]string `json:"error"`
+			}
+			ts.requestJSON(t, http.MethodGet, "/v1/movies?"+tt.query, "", http.StatusUnprocessableEntity, &env)
+			if got := env.Error[tt.key]; got != tt.want {
+				t.Errorf("got %s error %q; want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	var env struct {
+		Movies []struct {
+			Title string `json:"title"`
+		} `json:"movies"`
+	}
+	ts.requestJSON(t, http.MethodGet, "/v1/movies?year_min=2016&runtime_max=120", "", http.StatusOK, &env)
+	if len(env.Movies) != 1 {
+		t.Errorf("got movies %v; want Moana", env.Movies)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"movieDB/internal/data"
+	"movieDB/internal/jsonpatch"
+	"net/http"
+)
+
+// Media types of the patch documents accepted by updateMovieHandler alongside a plain partial movie.
+const (
+	jsonPatchMediaType  = "application/json-patch+json"
+	mergePatchMediaType = "application/merge-patch+json"
+)
+
+// patchMediaType returns the media type of the request body when it is one of the patch documents, or an empty
+// string for any other body, which is read as a partial movie.
+func patchMediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	switch mediaType {
+	case jsonPatchMediaType, mergePatchMediaType:
+		return mediaType
+	default:
+		return ""
+	}
+}
+
+// readMoviePatch applies the patch document in the request body to the editable fields of movie, as held by
+// data.MovieSnapshot, and returns the result. Errors wrapping jsonpatch.ErrPathNotFound or jsonpatch.ErrTestFailed
+// mean the patch does not fit the movie as it stands, any other error is a malformed request.
+func (app *application) readMoviePatch(w http.ResponseWriter, r *http.Request, mediaType string, movie *data.Movie) (data.MovieSnapshot, error) {
+	var snapshot data.MovieSnapshot
+
+	maxBytes := 1_048_576
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		if err.Error() == "http: request body too large" {
+			return snapshot, fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+		}
+		return snapshot, err
+	}
+
+	// Empty genres and external IDs are written out as an array and an object, so that patches can add to them.
+	current := data.SnapshotOf(movie)
+	if current.Genres == nil {
+		current.Genres = []string{}
+	}
+	if current.ExternalIDs == nil {
+		current.ExternalIDs = data.ExternalIDs{}
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return snapshot, err
+	}
+
+	if mediaType == jsonPatchMediaType {
+		doc, err = jsonpatch.Apply(doc, patch)
+	} else {
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	}
+	if err != nil {
+		return snapshot, err
+	}
+
+	// The patched document must still be a movie, so any field it does not know or of the wrong type is refused.
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&snapshot)
+	if err != nil {
+		return snapshot, fmt.Errorf("patched movie is invalid: %v", jsonError(err))
+	}
+
+	return snapshot, nil
+}
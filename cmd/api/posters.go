@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"mime"
+	"movieDB/internal/blob"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// uploadPosterHandler replaces the poster of a movie with the image sent in the "poster" field of a multipart form.
+// The original is stored before responding, the thumbnails in PosterWidths are generated in the background and
+// appear in the movie's posters as each is stored.
+func (app *application) uploadPosterHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := app.readPosterPart(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	ext := data.ValidatePoster(v, content)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	poster, err := data.NewPosterName(ext)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.blobs.Put(r.Context(), data.PosterKey(movie.ID, poster, data.PosterOriginal), bytes.NewReader(content))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	previous, err := app.models.Movies.SetPoster(movie.ID, poster)
+	if err != nil {
+		app.deletePosterFiles(r, movie.ID, poster)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if previous != "" {
+		app.deletePosterFiles(r, movie.ID, previous)
+	}
+
+	app.background(func() {
+		app.generateThumbnails(movie.ID, poster, content)
+	})
+
+	movie.Poster = poster
+	movie.PosterSizes = []string{data.PosterOriginal}
+	app.posterURLs(movie)
+	app.formatRuntimes(w, r, movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deletePosterHandler removes the poster of a movie along with its thumbnails.
+func (app *application) deletePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	previous, err := app.models.Movies.SetPoster(id, "")
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if previous == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.deletePosterFiles(r, id, previous)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "poster successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showImageHandler serves a file from the blob store. Keys hold the random name of their upload, so a key never
+// refers to different contents and responses may be cached indefinitely.
+func (app *application) showImageHandler(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(httprouter.ParamsFromContext(r.Context()).ByName("key"), "/")
+
+	f, err := app.blobs.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound), errors.Is(err, blob.ErrInvalidKey):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, f)
+	if err != nil {
+		// The status has been sent, all that is left is to record the failure.
+		app.logError(r, err)
+	}
+}
+
+// readPosterPart reads the "poster" part of a multipart form, skipping any other parts. At most one byte beyond
+// data.PosterMaxBytes is read so that ValidatePoster can report an oversized image. A nil slice is returned if the
+// form has no poster.
+func (app *application) readPosterPart(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// Allow for the part headers and any small fields alongside the image.
+	r.Body = http.MaxBytesReader(w, r.Body, data.PosterMaxBytes+1_048_576)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("body must be a multipart/form-data request")
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, posterReadError(err)
+		}
+
+		if part.FormName() != "poster" {
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(part, data.PosterMaxBytes+1))
+		if err != nil {
+			return nil, posterReadError(err)
+		}
+		return content, nil
+	}
+}
+
+// posterReadError translates an error from reading a multipart form into a message fit for the client.
+func posterReadError(err error) error {
+	if err.Error() == "http: request body too large" {
+		return fmt.Errorf("body must not be larger than %d bytes", data.PosterMaxBytes+1_048_576)
+	}
+	return fmt.Errorf("body contains a badly formatted multipart form: %v", err)
+}
+
+// generateThumbnails stores a JPEG thumbnail of the poster for each size in PosterWidths, recording each with
+// AddPosterSize once stored. It stops, removing the files of the upload, if the poster is replaced or removed in the
+// meantime. Errors are logged as there is no client left to report them to.
+func (app *application) generateThumbnails(movieID int64, poster string, content []byte) {
+	properties := map[string]string{"movie_id": strconv.FormatInt(movieID, 10), "poster": poster}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		app.logger.PrintError(err, properties)
+		return
+	}
+
+	sizes := make([]string, 0, len(data.PosterWidths))
+	for size := range data.PosterWidths {
+		sizes = append(sizes, size)
+	}
+	sort.Strings(sizes)
+
+	for _, size := range sizes {
+		var buf bytes.Buffer
+		err := jpeg.Encode(&buf, resizeImage(img, data.PosterWidths[size]), &jpeg.Options{Quality: 85})
+		if err != nil {
+			app.logger.PrintError(err, properties)
+			return
+		}
+
+		err = app.blobs.Put(context.Background(), data.PosterKey(movieID, poster, size), &buf)
+		if err != nil {
+			app.logger.PrintError(err, properties)
+			return
+		}
+
+		err = app.models.Movies.AddPosterSize(movieID, poster, size)
+		if err != nil {
+			if errors.Is(err, data.ErrEditConflict) {
+				// The poster was replaced or removed while the thumbnail was generated, whoever did so has already
+				// removed the files stored before it, so remove those stored since.
+				err = app.blobs.DeletePrefix(context.Background(), data.PosterUploadPrefix(movieID, poster))
+				if err == nil {
+					return
+				}
+			}
+			app.logger.PrintError(err, properties)
+			return
+		}
+	}
+}
+
+// deletePosterFiles removes every file of a poster upload. Failures leave orphaned files behind rather than failing
+// the request, so they are only logged.
+func (app *application) deletePosterFiles(r *http.Request, movieID int64, poster string) {
+	err := app.blobs.DeletePrefix(r.Context(), data.PosterUploadPrefix(movieID, poster))
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// posterURLs fills in the poster URLs of each movie from the sizes of its poster stored so far.
+func (app *application) posterURLs(movies ...*data.Movie) {
+	for _, movie := range movies {
+		keys := movie.PosterKeys()
+		if len(keys) == 0 {
+			continue
+		}
+
+		movie.Posters = make(map[string]string, len(keys))
+		for size, key := range keys {
+			movie.Posters[size] = app.blobs.URL(key)
+		}
+	}
+}
+
+// resizeImage scales img down to width pixels wide, keeping its aspect ratio, by averaging the source pixels covered
+// by each destination pixel. Transparent areas are flattened onto white since JPEG has no alpha channel. Images no
+// wider than width keep their size.
+func resizeImage(img image.Image, width int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	if srcW <= width {
+		return src
+	}
+
+	height := (srcH*width + srcW/2) / srcW
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, (y+1)*srcH/height
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, (x+1)*srcW/width
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			n := (x1 - x0) * (y1 - y0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+)
+
+// listMovieRatingsHandler lists the ratings and reviews of a movie, newest first by default.
+func (app *application) listMovieRatingsHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "-id"),
+		SortSafeList: []string{"id", "score"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ratings, metadata, err := app.models.Ratings.GetAllForMovie(movie.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"ratings": ratings, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createRatingHandler records the authenticated user's rating of a movie. Each user may rate a movie once, after
+// which the rating is changed with updateRatingHandler.
+func (app *application) createRatingHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Score  int32  `json:"score"`
+		Review string `json:"review"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rating := &data.Rating{
+		MovieID: movie.ID,
+		UserID:  app.contextGetUser(r).ID,
+		Score:   input.Score,
+		Review:  input.Review,
+	}
+
+	v := validator.New()
+
+	if data.ValidateRating(v, rating); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Ratings.Insert(rating)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateRating):
+			v.AddError("movie", "you have already rated this movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"rating": rating}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateRatingHandler replaces the score and review of the authenticated user's rating of a movie.
+func (app *application) updateRatingHandler(w http.ResponseWriter, r *http.Request) {
+	rating, ok := app.readOwnRating(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Score  int32  `json:"score"`
+		Review string `json:"review"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rating.Score = input.Score
+	rating.Review = input.Review
+
+	v := validator.New()
+
+	if data.ValidateRating(v, rating); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Ratings.Update(rating)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"rating": rating}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteRatingHandler withdraws the authenticated user's rating of a movie.
+func (app *application) deleteRatingHandler(w http.ResponseWriter, r *http.Request) {
+	rating, ok := app.readOwnRating(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Ratings.Delete(rating)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "rating successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readMovieParam reads the movie named by the id parameter, sending a 404 if it does not exist or is in the trash.
+func (app *application) readMovieParam(w http.ResponseWriter, r *http.Request) (*data.Movie, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return movie, true
+}
+
+// readOwnRating reads the authenticated user's rating of the movie named by the id parameter, sending a 404 if they
+// have not rated it.
+func (app *application) readOwnRating(w http.ResponseWriter, r *http.Request) (*data.Rating, bool) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	rating, err := app.models.Ratings.GetForUser(movie.ID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return rating, true
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+)
+
+// revisionUserID returns the ID of the authenticated user, to whom the revisions saved by the request are attributed,
+// or nil for an anonymous request.
+func (app *application) revisionUserID(r *http.Request) *int64 {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		return nil
+	}
+	return &user.ID
+}
+
+// listMovieRevisionsHandler lists the recorded versions of a movie, newest first by default. As with the movie itself,
+// a movie in the trash is not found.
+func (app *application) listMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "-version"),
+		SortSafeList: []string{"version"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	revisions, metadata, err := app.models.Revisions.GetAllForMovie(movie.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// diffMovieRevisionsHandler reports the fields which changed between the versions given by the from and to
+// parameters.
+func (app *application) diffMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	fromVersion := app.readInt32(qs, "from", 0, v)
+	toVersion := app.readInt32(qs, "to", 0, v)
+
+	v.Check(fromVersion > 0, "from", "must be a version greater than zero")
+	v.Check(toVersion > 0, "to", "must be a version greater than zero")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	from, err := app.models.Revisions.Get(movie.ID, fromVersion)
+	if err != nil {
+		app.revisionErrorResponse(w, r, err)
+		return
+	}
+
+	to, err := app.models.Revisions.Get(movie.ID, toVersion)
+	if err != nil {
+		app.revisionErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"from":    from.Version,
+		"to":      to.Version,
+		"changes": from.Snapshot.Diff(to.Snapshot),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revertMovieHandler restores the fields of a movie to those recorded at the version given by the version parameter.
+// The revert is saved as a new version, so it is subject to the same If-Match precondition and edit conflict check as
+// any other update.
+func (app *application) revertMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	version := app.readInt32(r.URL.Query(), "version", 0, v)
+	if v.Check(version > 0, "version", "must be a version greater than zero"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		app.revisionErrorResponse(w, r, err)
+		return
+	}
+
+	// A revert replaces the movie's fields as an update does, so it is held to the same precondition.
+	etag, err := app.currentMovieETag(w, r, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !app.checkIfMatch(w, r, etag) {
+		return
+	}
+
+	revision, err := app.models.Revisions.Get(id, version)
+	if err != nil {
+		app.revisionErrorResponse(w, r, err)
+		return
+	}
+
+	revision.Snapshot.Apply(movie)
+
+	// Snapshots taken before the genre taxonomy may hold genres which are no longer canonical.
+	err = app.resolveMovieGenres(v, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateMovie(v, movie); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Movies.Update(movie, data.RevisionRevert, app.revisionUserID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "must not contain an ID now used by another movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.presentMovies(w, r, fieldset{}, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	etag, err = movieETag(movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revisionErrorResponse sends a 404 for a missing movie or revision and a 500 for anything else.
+func (app *application) revisionErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		app.notFoundResponse(w, r)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
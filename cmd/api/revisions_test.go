@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"movieDB/internal/data"
+	"net/http"
+	"testing"
+)
+
+func TestListMovieRevisions(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	id := ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+	path := fmt.Sprintf("/v1/movies/%d", id)
+
+	ts.requestJSON(t, http.MethodPatch, path, `{"year":2017}`, http.StatusOK, nil)
+
+	var env struct {
+		Revisions []data.Revision `json:"revisions"`
+	}
+	ts.requestJSON(t, http.MethodGet, path+"/revisions", "", http.StatusOK, &env)
+
+	if len(env.Revisions) != 2 {
+		t.Fatalf("got %d revisions; want 2", len(env.Revisions))
+	}
+	for i, want := range []struct {
+		version int32
+		action  string
+		year    int32
+	}{
+		{2, data.RevisionUpdate, 2017},
+		{1, data.RevisionInsert, 2016},
+	} {
+		got := env.Revisions[i]
+		if got.Version != want.version || got.Action != want.action || got.Snapshot.Year != want.year {
+			t.Errorf("got revision %d %s of %d; want %d %s of %d", got.Version, got.Action, got.Snapshot.Year, want.version, want.action, want.year)
+		}
+		if got.UserID == nil {
+			t.Errorf("revision %d not attributed to the user", got.Version)
+		}
+	}
+
+	ts.requestJSON(t, http.MethodGet, "/v1/movies/999/revisions", "", http.StatusNotFound, nil)
+
+	// A movie in the trash is not found, although its revisions are kept until it is purged.
+	ts.requestJSON(t, http.MethodDelete, path, "", http.StatusOK, nil)
+	ts.requestJSON(t, http.MethodGet, path+"/revisions", "", http.StatusNotFound, nil)
+	ts.requestJSON(t, http.MethodGet, path+"/revisions/diff?from=1&to=2", "", http.StatusNotFound, nil)
+}
+
+func TestRevertMovieIfMatch(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	id := ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+	path := fmt.Sprintf("/v1/movies/%d", id)
+
+	etag := ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil).Get("ETag")
+	ts.requestJSON(t, http.MethodPatch, path, `{"year":2017}`, http.StatusOK, nil)
+
+	ts.requestJSON(t, http.MethodPost, path+"/revert?version=1", "", http.StatusPreconditionFailed, nil, "If-Match", etag)
+
+	ts.app.config.etag.strict = true
+	ts.requestJSON(t, http.MethodPost, path+"/revert?version=1", "", http.StatusPreconditionRequired, nil)
+
+	etag = ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil).Get("ETag")
+
+	var env struct {
+		Movie data.Movie `json:"movie"`
+	}
+	reverted := ts.requestJSON(t, http.MethodPost, path+"/revert?version=1", "", http.StatusOK, &env, "If-Match", etag).Get("ETag")
+	if env.Movie.Year != 2016 || env.Movie.Version != 3 {
+		t.Errorf("got year %d at version %d; want 2016 at version 3", env.Movie.Year, env.Movie.Version)
+	}
+
+	current := ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, nil).Get("ETag")
+	if reverted != current {
+		t.Errorf("revert sent ETag %s; GET sends %s", reverted, current)
+	}
+}
+
+func TestRevisionVersionsOutOfRange(t *testing.T) {
+	ts := newTestServer(t, "movies:read", "movies:write")
+	id := ts.createMovie(t, `{"title":"Moana","year":2016,"runtime":"107 mins","genres":["animation"]}`)
+	path := fmt.Sprintf("/v1/movies/%d", id)
+
+	ts.requestJSON(t, http.MethodPatch, path, `{"year":2017}`, http.StatusOK, nil)
+
+	// 4294967297 wraps around to version 1 as an int32.
+	tests := []struct {
+		method string
+		path   string
+		key    string
+	}{
+		{http.MethodGet, path + "/revisions/diff?from=4294967297&to=2", "from"},
+		{http.MethodGet, path + "/revisions/diff?from=1&to=4294967298", "to"},
+		{http.MethodPost, path + "/revert?version=4294967297", "version"},
+	}
+
+	for _, tt := range tests {
+		var env struct {
+			Error map[string]string `json:"error"`
+		}
+		ts.requestJSON(t, tt.method, tt.path, "", http.StatusUnprocessableEntity, &env)
+		if _, ok := env.Error[tt.key]; !ok {
+			t.Errorf("%s %s: got errors %v; want one for %s", tt.method, tt.path, env.Error, tt.key)
+		}
+	}
+
+	var env struct {
+		Movie data.Movie `json:"movie"`
+	}
+	ts.requestJSON(t, http.MethodGet, path, "", http.StatusOK, &env)
+	if env.Movie.Year != 2017 || env.Movie.Version != 2 {
+		t.Errorf("got year %d at version %d; want the movie left at 2017 and version 2", env.Movie.Year, env.Movie.Version)
+	}
+}
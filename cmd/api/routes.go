@@ -14,12 +14,82 @@ func (app *application) routes() http.Handler {
 	// register relevant routes
 	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requirePermission("movies:read", app.listMoviesHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.createMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requirePermission("movies:read", app.showMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.staticOrParam("id", map[string]http.HandlerFunc{
+		"trash":  app.requirePermission("movies:admin", app.listTrashedMoviesHandler),
+		"export": app.requirePermission("movies:export", app.exportMoviesHandler),
+	}, app.requirePermission("movies:read", app.showMovieHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id", app.staticOrParam("id", map[string]http.HandlerFunc{
+		"import": app.requirePermission("movies:write", app.importMoviesHandler),
+	}, app.methodNotAllowedResponse))
 	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requirePermission("movies:write", app.updateMovieHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.requirePermission("movies:write", app.deleteMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/similar", app.requirePermission("movies:read", app.listSimilarMoviesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/restore", app.requirePermission("movies:admin", app.restoreMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/revisions", app.requirePermission("movies:read", app.listMovieRevisionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/revisions/diff", app.requirePermission("movies:read", app.diffMovieRevisionsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/revert", app.requirePermission("movies:write", app.revertMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/credits", app.requirePermission("movies:read", app.listMovieCreditsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/credits", app.requirePermission("movies:write", app.createMovieCreditHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/credits/:credit_id", app.requirePermission("movies:write", app.deleteMovieCreditHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/titles", app.requirePermission("movies:read", app.listMovieTitlesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/titles", app.requirePermission("movies:write", app.createMovieTitleHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/titles/:title_id", app.requirePermission("movies:write", app.deleteMovieTitleHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/poster", app.requirePermission("movies:write", app.uploadPosterHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/poster", app.requirePermission("movies:write", app.deletePosterHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/ratings", app.requirePermission("movies:read", app.listMovieRatingsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/ratings", app.requireActivatedUser(app.createRatingHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/ratings", app.requireActivatedUser(app.updateRatingHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/ratings", app.requireActivatedUser(app.deleteRatingHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/people", app.requirePermission("movies:read", app.listPeopleHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/people", app.requirePermission("movies:write", app.createPersonHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.requirePermission("movies:read", app.showPersonHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/people/:id", app.requirePermission("movies:write", app.updatePersonHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/people/:id", app.requirePermission("movies:write", app.deletePersonHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/genres", app.requirePermission("movies:read", app.listGenresHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/genres", app.requirePermission("movies:admin", app.createGenreHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/genres/:id", app.requirePermission("movies:read", app.showGenreHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/genres/:id", app.requirePermission("movies:admin", app.updateGenreHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/genres/:id", app.requirePermission("movies:admin", app.deleteGenreHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/lists", app.requirePermission("lists:read", app.listListsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/lists", app.requirePermission("lists:write", app.createListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:id", app.requirePermission("lists:read", app.showListHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id", app.requirePermission("lists:write", app.updateListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id", app.requirePermission("lists:write", app.deleteListHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/lists/:id/entries", app.requirePermission("lists:write", app.addListEntryHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id/entries/:movie_id", app.requirePermission("lists:write", app.updateListEntryHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id/entries/:movie_id", app.requirePermission("lists:write", app.deleteListEntryHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/lists/:id/share", app.requirePermission("lists:write", app.shareListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id/share", app.requirePermission("lists:write", app.unshareListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/shared/lists/:token", app.showSharedListHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/images/*key", app.showImageHandler)
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler) // Idempotent
+	router.HandlerFunc(http.MethodPut, "/v1/users/password", app.updateUserPasswordHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/sessions", app.requireAuthenticatedUser(app.listSessionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/sessions", app.requireAuthenticatedUser(app.deleteAllSessionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/sessions/:id", app.requireAuthenticatedUser(app.deleteSessionHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
-	return app.recoverPanic(app.rateLimit(app.authenticate(router)))
+	router.HandlerFunc(http.MethodDelete, "/v1/tokens/authentication", app.requireAuthenticatedUser(app.deleteAuthenticationTokenHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/activation", app.createActivationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
+	return app.recoverPanic(app.rateLimit(app.authenticate(app.negotiateRuntimeFormat(router))))
+}
+
+// staticOrParam allows fixed path segments such as /v1/movies/trash to share a position with a named parameter, which
+// httprouter rejects as conflicting routes. The route is registered with the parameter and requests whose parameter
+// matches a key of static are handed to that handler instead of next.
+func (app *application) staticOrParam(param string, static map[string]http.HandlerFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value := httprouter.ParamsFromContext(r.Context()).ByName(param)
+		if handler, ok := static[value]; ok {
+			handler(w, r)
+			return
+		}
+		next(w, r)
+	}
 }
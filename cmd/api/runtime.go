@@ -0,0 +1,64 @@
+package main
+
+import (
+	"movieDB/internal/data"
+	"net/http"
+	"strings"
+)
+
+// readRuntimeFormat returns the format movie runtimes are written in for the request. The runtime_format query
+// parameter is used when given, false is returned if it names an unknown format. Otherwise the first runtime parameter
+// in the Accept header naming a known format is used, as in "application/json; runtime=iso8601", and failing that
+// the default. Unknown formats in the header are passed over as content negotiation is only a preference.
+func readRuntimeFormat(r *http.Request) (data.RuntimeFormat, bool) {
+	if value := r.URL.Query().Get("runtime_format"); value != "" {
+		format := data.RuntimeFormat(strings.ToLower(value))
+		return format, knownRuntimeFormat(format)
+	}
+
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		for _, param := range strings.Split(mediaRange, ";")[1:] {
+			parts := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "runtime") {
+				continue
+			}
+
+			format := data.RuntimeFormat(strings.ToLower(strings.Trim(parts[1], `"`)))
+			if knownRuntimeFormat(format) {
+				return format, true
+			}
+		}
+	}
+
+	return data.RuntimeFormatMins, true
+}
+
+// knownRuntimeFormat reports whether format is one of data.RuntimeFormats.
+func knownRuntimeFormat(format data.RuntimeFormat) bool {
+	for _, known := range data.RuntimeFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+// runtimeFormatNames lists data.RuntimeFormats for messages.
+func runtimeFormatNames() string {
+	names := make([]string, len(data.RuntimeFormats))
+	for i, format := range data.RuntimeFormats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatRuntimes sets the runtime of each movie to be written in the format chosen for the request by
+// negotiateRuntimeFormat.
+func (app *application) formatRuntimes(w http.ResponseWriter, r *http.Request, movies ...*data.Movie) {
+	w.Header().Add("Vary", "Accept")
+
+	format := app.contextGetRuntimeFormat(r)
+	for _, movie := range movies {
+		movie.RuntimeFormat = format
+	}
+}
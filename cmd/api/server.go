@@ -51,6 +51,7 @@ func (app *application) serve() error {
 			"addr": srv.Addr,
 		})
 
+		close(app.shutdown)
 		app.wg.Wait()
 		shutdownError <- nil
 
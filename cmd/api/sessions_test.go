@@ -0,0 +1,53 @@
+package main
+
+import (
+	"movieDB/internal/data"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// touchCounter counts the calls made to Touch.
+type touchCounter struct {
+	data.TokenStore
+	touches int
+}
+
+func (c *touchCounter) Touch(tokenPlaintext string) error {
+	c.touches++
+	return c.TokenStore.Touch(tokenPlaintext)
+}
+
+// staleTokens reads users as though the token they were read with was last used two SessionTouchIntervals ago.
+type staleTokens struct {
+	data.UserStore
+}
+
+func (s staleTokens) GetForToken(tokenScope, tokenPlaintext string) (*data.User, error) {
+	user, err := s.UserStore.GetForToken(tokenScope, tokenPlaintext)
+	if err == nil {
+		user.TokenLastUsedAt = time.Now().Add(-2 * data.SessionTouchInterval)
+	}
+	return user, err
+}
+
+func TestAuthenticateTouchesOnlyStaleSessions(t *testing.T) {
+	ts := newTestServer(t, "movies:read")
+
+	counter := &touchCounter{TokenStore: ts.app.models.Tokens}
+	ts.app.models.Tokens = counter
+
+	// The token was last used when it was created, a moment ago.
+	ts.requestJSON(t, http.MethodGet, "/v1/movies", "", http.StatusOK, nil)
+	ts.requestJSON(t, http.MethodGet, "/v1/movies", "", http.StatusOK, nil)
+	if counter.touches != 0 {
+		t.Errorf("got %d touches of a recently used token; want 0", counter.touches)
+	}
+
+	ts.app.models.Users = staleTokens{UserStore: ts.app.models.Users}
+
+	ts.requestJSON(t, http.MethodGet, "/v1/movies", "", http.StatusOK, nil)
+	if counter.touches != 1 {
+		t.Errorf("got %d touches of a stale token; want 1", counter.touches)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"movieDB/internal/blob"
+	"movieDB/internal/data"
+	"movieDB/internal/jsonlog"
+	"movieDB/internal/mailer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServer serves the API from the in-memory models, authenticating requests as a single activated user.
+type testServer struct {
+	app     *application
+	handler http.Handler
+	token   string // authentication token of the user
+}
+
+// newTestServer returns a testServer whose user holds the given permissions. Mail is sent to an address nothing
+// listens on, so sending fails and is logged rather than delivered.
+func newTestServer(t *testing.T, permissions ...string) *testServer {
+	t.Helper()
+
+	blobs, err := blob.NewFileStore(t.TempDir(), "/v1/images/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		logger:             jsonlog.New(io.Discard, jsonlog.LevelError),
+		models:             data.NewMemoryModels(),
+		mailer:             mailer.New("127.0.0.1", 1, "", "", "test@example.com"),
+		blobs:              blobs,
+		shutdown:           make(chan struct{}),
+		activationThrottle: newThrottle(time.Minute),
+	}
+	app.config.search.config = "simple"
+	app.config.titles.language = "en"
+
+	user := &data.User{Name: "Test User", Email: "test@example.com", Activated: true}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Permissions.AddForUser(user.ID, permissions...); err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.models.Tokens.New(user.ID, time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(app.wg.Wait)
+	return &testServer{app: app, handler: app.routes(), token: token.Plaintext}
+}
+
+// request sends a request as the user, headers being pairs of names and values, and returns the response.
+func (ts *testServer) request(t *testing.T, method, path, body string, headers ...string) *http.Response {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if ts.token != "" {
+		r.Header.Set("Authorization", "Bearer "+ts.token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	ts.handler.ServeHTTP(w, r)
+	return w.Result()
+}
+
+// requestJSON sends a request as request does, checks the status of the response and decodes its body into dst,
+// unless dst is nil.
+func (ts *testServer) requestJSON(t *testing.T, method, path, body string, status int, dst interface{}, headers ...string) http.Header {
+	t.Helper()
+
+	res := ts.request(t, method, path, body, headers...)
+	defer res.Body.Close()
+
+	js, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != status {
+		t.Fatalf("%s %s: got status %d; want %d\n%s", method, path, res.StatusCode, status, js)
+	}
+	if dst != nil {
+		if err := json.Unmarshal(js, dst); err != nil {
+			t.Fatalf("%s %s: %v\n%s", method, path, err, js)
+		}
+	}
+	return res.Header
+}
+
+// createMovie adds a movie through the API, returning its ID.
+func (ts *testServer) createMovie(t *testing.T, body string) int64 {
+	t.Helper()
+
+	var env struct {
+		Movie struct {
+			ID int64 `json:"id"`
+		} `json:"movie"`
+	}
+	ts.requestJSON(t, http.MethodPost, "/v1/movies?force=true", body, http.StatusCreated, &env)
+	return env.Movie.ID
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle allows an action once per interval for each key, such as the address an email is sent to. Keys are only
+// held while throttled, so its size is bounded by the number of keys used within an interval.
+type throttle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     map[string]time.Time
+}
+
+func newThrottle(interval time.Duration) *throttle {
+	return &throttle{interval: interval, last: make(map[string]time.Time)}
+}
+
+// allow reports whether the action may be taken for key, recording it as taken if so.
+func (t *throttle) allow(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for k, last := range t.last {
+		if now.Sub(last) >= t.interval {
+			delete(t.last, k)
+		}
+	}
+
+	if _, throttled := t.last[key]; throttled {
+		return false
+	}
+
+	t.last[key] = now
+	return true
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"movieDB/internal/data"
+	"movieDB/internal/validator"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listMovieTitlesHandler lists the alternate titles of a movie.
+func (app *application) listMovieTitlesHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	titles, err := app.models.Titles.GetAllForMovie(movie.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"titles": titles}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createMovieTitleHandler adds an alternate title to a movie for a language and, optionally, a region.
+func (app *application) createMovieTitleHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := app.readMovieParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title    string `json:"title"`
+		Language string `json:"language"`
+		Region   string `json:"region"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	title := &data.Title{
+		MovieID: movie.ID,
+		Title:   input.Title,
+	}
+	title.Language, title.Region = data.NormalizeLocale(input.Language, input.Region)
+
+	v := validator.New()
+
+	if data.ValidateTitle(v, title); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Titles.Insert(title)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateTitle):
+			v.AddError("language", "the movie already has a title for this language and region")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"title": title}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteMovieTitleHandler removes an alternate title from a movie.
+func (app *application) deleteMovieTitleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	titleID, err := app.readIDParamNamed(r, "title_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Titles.Delete(id, titleID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "title successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// localizeTitles replaces the title of each movie with the alternate title best matching the Accept-Language header
+// of the request, recording its language tag in TitleLocale. Movies without a matching alternate title keep their
+// default title, which is taken to be in the language set by -titles-language. Responses to writes are localized too,
+// through presentMovies, so they carry the same title and ETag a GET would.
+func (app *application) localizeTitles(w http.ResponseWriter, r *http.Request, movies ...*data.Movie) error {
+	w.Header().Add("Vary", "Accept-Language")
+
+	ranges := acceptLanguages(r.Header.Get("Accept-Language"))
+	if len(ranges) == 0 || len(movies) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	titles, err := app.models.Titles.GetAllForMovies(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		if title := matchTitle(titles[movie.ID], ranges, app.config.titles.language); title != nil {
+			movie.Title = title.Title
+			movie.TitleLocale = title.Locale()
+		}
+	}
+
+	return nil
+}
+
+// languageRange is a language tag from Accept-Language split into its language and region, both lower case. The
+// wildcard * has an empty language.
+type languageRange struct {
+	language string
+	region   string
+}
+
+// acceptLanguages parses an Accept-Language header into language ranges, most preferred first. Ranges with a
+// quality of zero or which cannot be parsed are dropped, as are any which follow the wildcard, since the wildcard
+// asks for the default title.
+func acceptLanguages(header string) []languageRange {
+	type weighted struct {
+		languageRange
+		q float64
+	}
+
+	var candidates []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				value, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					value = 0
+				}
+				q = value
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		var lr languageRange
+		if tag != "*" {
+			subtags := strings.Split(tag, "-")
+			lr.language = subtags[0]
+			// The region is the first two letter or three digit subtag after the language, skipping any script.
+			for _, subtag := range subtags[1:] {
+				if validator.Matches(strings.ToUpper(subtag), data.RegionRX) {
+					lr.region = subtag
+					break
+				}
+			}
+		}
+		candidates = append(candidates, weighted{lr, q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	var ranges []languageRange
+	for _, candidate := range candidates {
+		if candidate.language == "" {
+			break
+		}
+		ranges = append(ranges, candidate.languageRange)
+	}
+	return ranges
+}
+
+// matchTitle returns the title best matching ranges, or nil if the default title, in defaultLanguage, should be used.
+// For each range in turn a title for the same language and region is preferred, then the title for the language as
+// a whole, then the title for any region of the language. A range of the default language which has no title for
+// its region is served by the default title.
+func matchTitle(titles []*data.Title, ranges []languageRange, defaultLanguage string) *data.Title {
+	for _, lr := range ranges {
+		var general, regional *data.Title
+		for _, title := range titles {
+			if title.Language != lr.language {
+				continue
+			}
+			switch {
+			case lr.region != "" && strings.EqualFold(title.Region, lr.region):
+				return title
+			case title.Region == "":
+				if general == nil {
+					general = title
+				}
+			default:
+				if regional == nil {
+					regional = title
+				}
+			}
+		}
+
+		if strings.EqualFold(lr.language, defaultLanguage) {
+			return nil
+		}
+		if general != nil {
+			return general
+		}
+		if regional != nil {
+			return regional
+		}
+	}
+	return nil
+}
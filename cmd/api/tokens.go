@@ -4,7 +4,9 @@ import (
 	"errors"
 	"movieDB/internal/data"
 	"movieDB/internal/validator"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -52,10 +54,16 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	// Password correct, generate a 24-hour Authentication token.
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	// Password correct, generate a 24-hour Authentication token. The client's address and user agent are kept so the
+	// user can tell their sessions apart, see listSessionsHandler.
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	token, err := app.models.Tokens.NewSession(user.ID, 24*time.Hour, ip, r.UserAgent())
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	// Encode the token and return to the user as json. Status: 201 Created
@@ -64,3 +72,151 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// deleteAuthenticationTokenHandler logs out by revoking the authentication token the request was made with. The
+// user's other sessions are left alone, see deleteAllSessionsHandler.
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.models.Tokens.Delete(data.ScopeAuthentication, app.contextGetToken(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			// Revoked by a concurrent request since the token was authenticated.
+			app.invalidAuthenticationResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been logged out"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createActivationTokenHandler replaces the activation tokens of an unactivated account with a fresh one and emails
+// it, for when the welcome email was lost or its token has expired. As with createPasswordResetTokenHandler the
+// response does not reveal whether the email belongs to an account. Emails to the same address are throttled to
+// one per activation.resendInterval, whether or not it belongs to an account.
+func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The email column is case-insensitive, so is the throttle.
+	if !app.activationThrottle.allow(strings.ToLower(input.Email)) {
+		app.rateLimitExceededResponse(w, r)
+		return
+	}
+
+	app.background(func() {
+		user, err := app.models.Users.GetByEmail(input.Email)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				app.logger.PrintError(err, nil)
+			}
+			return
+		}
+
+		if user.Activated {
+			return
+		}
+
+		// Only the latest token may be used, so a lost email cannot activate the account later.
+		err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		templateData := map[string]interface{}{
+			"activationToken": token.Plaintext,
+		}
+
+		err = app.mailer.Send(user.Email, "token_activation.tmpl", templateData)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	env := envelope{"message": "an email will be sent to you containing activation instructions"}
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createPasswordResetTokenHandler emails a 45-minute password reset token to the owner of an activated account. The
+// response is the same whether or not the email belongs to an account, and the account is looked up in the
+// background, so neither the response nor its timing reveal which emails are registered.
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.background(func() {
+		user, err := app.models.Users.GetByEmail(input.Email)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				app.logger.PrintError(err, nil)
+			}
+			return
+		}
+
+		// Only activated accounts may be reset, an unactivated account has to be activated first.
+		if !user.Activated {
+			return
+		}
+
+		token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		templateData := map[string]interface{}{
+			"passwordResetToken": token.Plaintext,
+		}
+
+		err = app.mailer.Send(user.Email, "token_password_reset.tmpl", templateData)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	env := envelope{"message": "an email will be sent to you containing password reset instructions"}
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Package blob stores opaque files, such as uploaded images, under slash separated keys. Store is implemented by
+FileStore on the local filesystem; other backends such as object storage can be added behind the same interface.
+*/
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned by Get when no file is stored under the key.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for keys which are empty, absolute or contain empty, "." or ".." segments.
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store describes the operations available on a blob store.
+type Store interface {
+	// Put stores the contents of r under key, replacing any existing file. Readers never observe a partial file.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the file stored under key, returning ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// DeletePrefix removes every file whose key lies beneath prefix, which must end with a slash as in "posters/1/".
+	// Removing nothing is not an error.
+	DeletePrefix(ctx context.Context, prefix string) error
+	// URL returns the address clients use to fetch the file stored under key.
+	URL(key string) string
+}
+
+// ValidKey reports whether key may be used with a Store. Keys are relative paths of non-empty segments which are
+// neither "." nor "..", so they cannot escape the root of a store.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"time"
+)
+
+// ErrDuplicateCredit is returned when a person already holds the same credit on a movie.
+var ErrDuplicateCredit = errors.New("duplicate credit")
+
+// CreditRoles holds the roles a person may be credited with.
+var CreditRoles = []string{"director", "writer", "cast"}
+
+// Credit links a person to a movie. Character and Billing apply to the cast only, billing orders the cast list with
+// the top billed first.
+type Credit struct {
+	ID        int64  `json:"id"`
+	MovieID   int64  `json:"movie_id"`
+	PersonID  int64  `json:"person_id"`
+	Name      string `json:"name"` // the name of the person, read from people
+	Role      string `json:"role"`
+	Character string `json:"character,omitempty"`
+	Billing   int32  `json:"billing,omitempty"`
+}
+
+// CreditModel holds the database pool for the movie_credits table.
+type CreditModel struct {
+	DB *sql.DB
+}
+
+// Insert credits a person on a movie. ErrRecordNotFound is returned if either does not exist and ErrDuplicateCredit
+// if the person already holds the credit. The Name of the credited person is populated.
+func (m CreditModel) Insert(credit *Credit) error {
+	query := `
+	INSERT INTO movie_credits (movie_id, person_id, role, character, billing)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, (SELECT name FROM people WHERE id = $2)`
+
+	args := []interface{}{credit.MovieID, credit.PersonID, credit.Role, credit.Character, credit.Billing}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&credit.ID, &credit.Name)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "movie_credits" violates foreign key constraint "movie_credits_movie_id_fkey"`:
+			return ErrRecordNotFound
+		case err.Error() == `pq: insert or update on table "movie_credits" violates foreign key constraint "movie_credits_person_id_fkey"`:
+			return ErrRecordNotFound
+		case err.Error() == `pq: duplicate key value violates unique constraint "movie_credits_movie_id_person_id_role_character_key"`:
+			return ErrDuplicateCredit
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a single credit from a movie.
+func (m CreditModel) Delete(movieID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM movie_credits
+	WHERE id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForMovie lists the credits of a movie: directors, then writers, then the cast in billing order.
+func (m CreditModel) GetAllForMovie(movieID int64) ([]*Credit, error) {
+	query := `
+	SELECT c.id, c.movie_id, c.person_id, p.name, c.role, c.character, c.billing
+	FROM movie_credits c
+	INNER JOIN people p ON p.id = c.person_id
+	WHERE c.movie_id = $1
+	ORDER BY array_position(ARRAY['director', 'writer', 'cast'], c.role), c.billing, c.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+	for rows.Next() {
+		var credit Credit
+		err := rows.Scan(
+			&credit.ID,
+			&credit.MovieID,
+			&credit.PersonID,
+			&credit.Name,
+			&credit.Role,
+			&credit.Character,
+			&credit.Billing,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &credit)
+	}
+
+	return credits, rows.Err()
+}
+
+// GetAllForMovies retrieves the credits of several movies at once, keyed by movie ID and ordered as GetAllForMovie
+// orders them. Movies without credits are absent from the map.
+func (m CreditModel) GetAllForMovies(movieIDs []int64) (map[int64][]*Credit, error) {
+	query := `
+	SELECT c.id, c.movie_id, c.person_id, p.name, c.role, c.character, c.billing
+	FROM movie_credits c
+	INNER JOIN people p ON p.id = c.person_id
+	WHERE c.movie_id = ANY($1)
+	ORDER BY c.movie_id, array_position(ARRAY['director', 'writer', 'cast'], c.role), c.billing, c.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := make(map[int64][]*Credit)
+	for rows.Next() {
+		var credit Credit
+		err := rows.Scan(
+			&credit.ID,
+			&credit.MovieID,
+			&credit.PersonID,
+			&credit.Name,
+			&credit.Role,
+			&credit.Character,
+			&credit.Billing,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits[credit.MovieID] = append(credits[credit.MovieID], &credit)
+	}
+
+	return credits, rows.Err()
+}
+
+// ValidateCredit checks the fields of a credit before it is saved. Only the cast have a character and billing.
+func ValidateCredit(v *validator.Validator, credit *Credit) {
+	v.Check(credit.PersonID > 0, "person_id", "must be provided")
+	v.Check(validator.In(credit.Role, CreditRoles...), "role", "must be one of director, writer or cast")
+
+	if credit.Role == "cast" {
+		v.Check(credit.Character != "", "character", "must be provided for the cast")
+		v.Check(len(credit.Character) <= 500, "character", "must not be longer than 500 bytes")
+		v.Check(credit.Billing >= 1, "billing", "must be greater than zero for the cast")
+		v.Check(credit.Billing <= 1000, "billing", "must be a maximum of 1000")
+	} else {
+		v.Check(credit.Character == "", "character", "must only be provided for the cast")
+		v.Check(credit.Billing == 0, "billing", "must only be provided for the cast")
+	}
+}
@@ -0,0 +1,204 @@
+package data
+
+import (
+	"fmt"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"strings"
+	"time"
+)
+
+// SearchConfigs lists the PostgreSQL text search configurations which may be used to search titles. Each one has a
+// matching GIN index on the movies table, see migration 000008.
+var SearchConfigs = []string{"simple", "english", "french", "german", "spanish"}
+
+// headlineOptions configures ts_headline to mark every match within the title.
+const headlineOptions = "StartSel=<b>, StopSel=</b>, HighlightAll=true"
+
+// GenresMatchSafeList holds the ways Genres may be matched: "all" requires every genre, "any" requires at least one.
+var GenresMatchSafeList = []string{"all", "any"}
+
+// MovieCriteria holds the conditions a movie must satisfy to be returned by GetAll. Zero values leave a condition
+// unset.
+type MovieCriteria struct {
+	Title        string   // full-text query on the title, accepts websearch_to_tsquery syntax
+	Genres       []string // the movie must have these genres, see GenresMatch
+	GenresMatch  string   // "all" (the default) or "any" of Genres
+	GenresNot    []string // the movie must have none of these genres
+	YearMin      int32
+	YearMax      int32
+	RuntimeMin   int32
+	RuntimeMax   int32
+	Person       int64      // the movie must credit this person, see CreditModel
+	RatingMin    int32      // the movie's average rating must be at least this, unrated movies never match
+	SearchConfig string     // text search configuration used to parse Title, one of SearchConfigs
+	Deleted      bool       // match movies in the trash rather than live movies
+	Similar      *SimilarTo // match movies similar to this one, scoring each by its similarity
+}
+
+// filterQuery accumulates the WHERE conditions of a query on the movies table along with their positional arguments.
+// Conditions are only added for the criteria actually supplied, which keeps the resulting SQL index-friendly.
+type filterQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg appends a query argument, returning its placeholder.
+func (q *filterQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where adds a condition, conditions are combined with AND.
+func (q *filterQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// clause returns the combined conditions for use after WHERE.
+func (q *filterQuery) clause() string {
+	if len(q.conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(q.conditions, "\n\tAND ")
+}
+
+// titleSearch holds the SQL expressions used to rank and highlight a title search, along with the similarity score
+// when matching similar movies.
+type titleSearch struct {
+	rank       string
+	headline   string
+	similarity string
+}
+
+// apply adds the conditions for criteria to q. The returned expressions compute the rank, headline and similarity of
+// each row, they are constants when there is no title search or similar movie.
+func (c MovieCriteria) apply(q *filterQuery) (titleSearch, error) {
+	search := titleSearch{rank: "0", headline: "''", similarity: "0"}
+
+	if c.Deleted {
+		q.where("deleted_at IS NOT NULL")
+	} else {
+		q.where("deleted_at IS NULL")
+	}
+
+	if c.Title != "" {
+		// The configuration is interpolated so the planner can match the expression indexes, so it must be safelisted.
+		if !validator.In(c.SearchConfig, SearchConfigs...) {
+			return search, fmt.Errorf("unsupported search configuration %q", c.SearchConfig)
+		}
+
+		document := fmt.Sprintf("to_tsvector('%s', title)", c.SearchConfig)
+		tsquery := fmt.Sprintf("websearch_to_tsquery('%s', %s)", c.SearchConfig, q.arg(c.Title))
+
+		// Alternate titles match too, the movie taking the rank of its best matching title. The headline is of the
+		// default title when it matches, otherwise of the best matching alternate title.
+		alternate := fmt.Sprintf("FROM movie_titles t WHERE t.movie_id = movies.id AND to_tsvector('%s', t.title) @@ %s",
+			c.SearchConfig, tsquery)
+
+		q.where(fmt.Sprintf("(%s @@ %s OR EXISTS(SELECT 1 %s))", document, tsquery, alternate))
+		search.rank = fmt.Sprintf("GREATEST(ts_rank(%s, %s), COALESCE((SELECT max(ts_rank(to_tsvector('%s', t.title), %s)) %s), 0))",
+			document, tsquery, c.SearchConfig, tsquery, alternate)
+		search.headline = fmt.Sprintf(`CASE WHEN %s @@ %s THEN ts_headline('%s', title, %s, '%s')
+		ELSE (SELECT ts_headline('%s', t.title, %s, '%s') %s ORDER BY ts_rank(to_tsvector('%s', t.title), %s) DESC, t.id LIMIT 1) END`,
+			document, tsquery, c.SearchConfig, tsquery, headlineOptions,
+			c.SearchConfig, tsquery, headlineOptions, alternate, c.SearchConfig, tsquery)
+	}
+
+	// @> and && are both served by the GIN index on genres.
+	if len(c.Genres) > 0 {
+		operator := "@>"
+		if c.GenresMatch == "any" {
+			operator = "&&"
+		}
+		q.where(fmt.Sprintf("genres %s %s", operator, q.arg(pq.Array(c.Genres))))
+	}
+	if len(c.GenresNot) > 0 {
+		q.where(fmt.Sprintf("NOT genres && %s", q.arg(pq.Array(c.GenresNot))))
+	}
+
+	// Plain comparisons, rather than BETWEEN with sentinel values, let the year and runtime btree indexes be used.
+	if c.YearMin != 0 {
+		q.where(fmt.Sprintf("year >= %s", q.arg(c.YearMin)))
+	}
+	if c.YearMax != 0 {
+		q.where(fmt.Sprintf("year <= %s", q.arg(c.YearMax)))
+	}
+	if c.RuntimeMin != 0 {
+		q.where(fmt.Sprintf("runtime >= %s", q.arg(c.RuntimeMin)))
+	}
+	if c.RuntimeMax != 0 {
+		q.where(fmt.Sprintf("runtime <= %s", q.arg(c.RuntimeMax)))
+	}
+
+	if c.RatingMin != 0 {
+		q.where(fmt.Sprintf("rating_avg >= %s", q.arg(c.RatingMin)))
+	}
+
+	if c.Person != 0 {
+		q.where(fmt.Sprintf("id IN (SELECT movie_id FROM movie_credits WHERE person_id = %s)", q.arg(c.Person)))
+	}
+
+	if c.Similar != nil {
+		search.similarity = c.Similar.apply(q)
+	}
+
+	return search, nil
+}
+
+// matches reports whether movie satisfies every condition of c other than the title search, which is evaluated
+// separately using parseTextQuery. It is the in-memory counterpart of apply.
+func (c MovieCriteria) matches(movie *Movie) bool {
+	switch {
+	case c.Deleted != (movie.DeletedAt != nil):
+		return false
+	case len(c.Genres) > 0 && c.GenresMatch == "any" && !containsAny(movie.Genres, c.Genres):
+		return false
+	case len(c.Genres) > 0 && c.GenresMatch != "any" && !containsAll(movie.Genres, c.Genres):
+		return false
+	case containsAny(movie.Genres, c.GenresNot):
+		return false
+	case c.YearMin != 0 && movie.Year < c.YearMin, c.YearMax != 0 && movie.Year > c.YearMax:
+		return false
+	case c.RuntimeMin != 0 && int32(movie.Runtime) < c.RuntimeMin:
+		return false
+	case c.RuntimeMax != 0 && int32(movie.Runtime) > c.RuntimeMax:
+		return false
+	case c.RatingMin != 0 && movie.Rating < float32(c.RatingMin):
+		return false
+	}
+	return true
+}
+
+// ValidateMovieCriteria checks the search conditions passed to GetAll.
+func ValidateMovieCriteria(v *validator.Validator, criteria MovieCriteria) {
+	v.Check(len(criteria.Title) <= 500, "title", "must not be longer than 500 bytes")
+	v.Check(validator.In(criteria.SearchConfig, SearchConfigs...), "search_config", "invalid search configuration")
+	v.Check(validator.In(criteria.GenresMatch, GenresMatchSafeList...), "genres_match", "must be all or any")
+
+	v.Check(validator.Unique(criteria.GenresNot), "genres_not", "must not contain duplicate values")
+	v.Check(!containsAny(criteria.Genres, criteria.GenresNot), "genres_not", "must not contain a genre listed in genres")
+
+	currentYear := int32(time.Now().Year())
+	v.Check(criteria.YearMin == 0 || criteria.YearMin >= 1888, "year_min", "must be greater than 1888")
+	v.Check(criteria.YearMin <= currentYear, "year_min", "cannot be in the future")
+	v.Check(criteria.YearMax == 0 || criteria.YearMax >= 1888, "year_max", "must be greater than 1888")
+	v.Check(criteria.YearMax <= currentYear, "year_max", "cannot be in the future")
+	v.Check(criteria.YearMin == 0 || criteria.YearMax == 0 || criteria.YearMin <= criteria.YearMax, "year_max", "must not be less than year_min")
+
+	v.Check(criteria.RuntimeMin >= 0, "runtime_min", "must be a positive integer")
+	v.Check(criteria.RuntimeMax >= 0, "runtime_max", "must be a positive integer")
+	v.Check(criteria.RuntimeMin == 0 || criteria.RuntimeMax == 0 || criteria.RuntimeMin <= criteria.RuntimeMax, "runtime_max", "must not be less than runtime_min")
+
+	v.Check(criteria.Person >= 0, "person", "must be a positive integer")
+	v.Check(criteria.RatingMin >= 0 && criteria.RatingMin <= RatingMax, "rating_min", fmt.Sprintf("must be between 0 and %d", RatingMax))
+}
+
+// containsAny reports whether set and values share at least one value, matching the PostgreSQL && array operator.
+func containsAny(set, values []string) bool {
+	for _, value := range values {
+		if containsAll(set, []string{value}) {
+			return true
+		}
+	}
+	return false
+}
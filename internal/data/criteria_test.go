@@ -0,0 +1,153 @@
+package data
+
+import (
+	"movieDB/internal/validator"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMovieCriteriaApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria MovieCriteria
+		want     []string // conditions, in order
+		args     int
+	}{
+		{"none", MovieCriteria{}, []string{"deleted_at IS NULL"}, 0},
+		{"trash", MovieCriteria{Deleted: true}, []string{"deleted_at IS NOT NULL"}, 0},
+		{
+			"genres",
+			MovieCriteria{Genres: []string{"drama"}, GenresNot: []string{"horror"}},
+			[]string{"deleted_at IS NULL", "genres @> $1", "NOT genres && $2"},
+			2,
+		},
+		{
+			"any genre",
+			MovieCriteria{Genres: []string{"drama", "comedy"}, GenresMatch: "any"},
+			[]string{"deleted_at IS NULL", "genres && $1"},
+			1,
+		},
+		{
+			"ranges",
+			MovieCriteria{YearMin: 1990, YearMax: 2000, RuntimeMax: 120, RatingMin: 7},
+			[]string{"deleted_at IS NULL", "year >= $1", "year <= $2", "runtime <= $3", "rating_avg >= $4"},
+			4,
+		},
+		{
+			"person",
+			MovieCriteria{Person: 4},
+			[]string{"deleted_at IS NULL", "id IN (SELECT movie_id FROM movie_credits WHERE person_id = $1)"},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		var q filterQuery
+		search, err := tt.criteria.apply(&q)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if got := strings.Join(q.conditions, "; "); got != strings.Join(tt.want, "; ") {
+			t.Errorf("%s: got conditions %q; want %q", tt.name, q.conditions, tt.want)
+		}
+		if len(q.args) != tt.args {
+			t.Errorf("%s: got %d args; want %d", tt.name, len(q.args), tt.args)
+		}
+		if search.rank != "0" || search.similarity != "0" {
+			t.Errorf("%s: got rank %q and similarity %q; want constants", tt.name, search.rank, search.similarity)
+		}
+	}
+}
+
+func TestMovieCriteriaApplyTitle(t *testing.T) {
+	var q filterQuery
+	search, err := MovieCriteria{Title: "moana", SearchConfig: "english"}.apply(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.args) != 1 || q.args[0] != "moana" {
+		t.Errorf("got args %v; want the title query alone", q.args)
+	}
+	if !strings.Contains(search.rank, "to_tsvector('english', title)") {
+		t.Errorf("rank %q does not use the english configuration", search.rank)
+	}
+
+	// The configuration is interpolated into the query, so anything outside SearchConfigs is refused.
+	_, err = MovieCriteria{Title: "moana", SearchConfig: "english'); --"}.apply(&filterQuery{})
+	if err == nil {
+		t.Error("got no error for an unsupported search configuration")
+	}
+}
+
+func TestMovieCriteriaMatches(t *testing.T) {
+	deletedAt := time.Now()
+	movie := &Movie{Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}, Rating: 7.5}
+
+	tests := []struct {
+		name     string
+		criteria MovieCriteria
+		movie    *Movie
+		want     bool
+	}{
+		{"none", MovieCriteria{}, movie, true},
+		{"trash", MovieCriteria{Deleted: true}, movie, false},
+		{"in the trash", MovieCriteria{Deleted: true}, &Movie{DeletedAt: &deletedAt}, true},
+		{"all genres", MovieCriteria{Genres: []string{"animation", "adventure"}}, movie, true},
+		{"missing genre", MovieCriteria{Genres: []string{"animation", "drama"}}, movie, false},
+		{"any genre", MovieCriteria{Genres: []string{"animation", "drama"}, GenresMatch: "any"}, movie, true},
+		{"excluded genre", MovieCriteria{GenresNot: []string{"adventure"}}, movie, false},
+		{"year range", MovieCriteria{YearMin: 2010, YearMax: 2016}, movie, true},
+		{"before year range", MovieCriteria{YearMin: 2017}, movie, false},
+		{"runtime range", MovieCriteria{RuntimeMin: 90, RuntimeMax: 100}, movie, false},
+		{"rating", MovieCriteria{RatingMin: 7}, movie, true},
+		{"unrated", MovieCriteria{RatingMin: 1}, &Movie{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.criteria.matches(tt.movie); got != tt.want {
+			t.Errorf("%s: got %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMovieCriteria(t *testing.T) {
+	valid := MovieCriteria{SearchConfig: "simple", GenresMatch: "all"}
+
+	tests := []struct {
+		name      string
+		modify    func(c *MovieCriteria)
+		wantError string
+	}{
+		{"valid", func(c *MovieCriteria) {}, ""},
+		{"search config", func(c *MovieCriteria) { c.SearchConfig = "klingon" }, "search_config"},
+		{"genres match", func(c *MovieCriteria) { c.GenresMatch = "some" }, "genres_match"},
+		{"genre included and excluded", func(c *MovieCriteria) { c.Genres, c.GenresNot = []string{"drama"}, []string{"drama"} }, "genres_not"},
+		{"year before film", func(c *MovieCriteria) { c.YearMin = 1800 }, "year_min"},
+		{"future year", func(c *MovieCriteria) { c.YearMax = int32(time.Now().Year() + 1) }, "year_max"},
+		{"inverted years", func(c *MovieCriteria) { c.YearMin, c.YearMax = 2000, 1990 }, "year_max"},
+		{"negative runtime", func(c *MovieCriteria) { c.RuntimeMin = -1 }, "runtime_min"},
+		{"inverted runtimes", func(c *MovieCriteria) { c.RuntimeMin, c.RuntimeMax = 120, 90 }, "runtime_max"},
+		{"rating", func(c *MovieCriteria) { c.RatingMin = RatingMax + 1 }, "rating_min"},
+	}
+
+	for _, tt := range tests {
+		criteria := valid
+		tt.modify(&criteria)
+
+		v := validator.New()
+		ValidateMovieCriteria(v, criteria)
+
+		if tt.wantError == "" {
+			if !v.Valid() {
+				t.Errorf("%s: got errors %v; want none", tt.name, v.Errors)
+			}
+			continue
+		}
+		if _, ok := v.Errors[tt.wantError]; !ok {
+			t.Errorf("%s: got errors %v; want one for %s", tt.name, v.Errors, tt.wantError)
+		}
+	}
+}
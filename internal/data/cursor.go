@@ -0,0 +1,158 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded or was issued for a different sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor identifies the row at the edge of a page for keyset pagination. It records the value of each sort key for
+// that row, other than the id which is recorded apart as it is always the last key. Backward cursors page towards
+// the start of the result set. Clients treat the encoded form as opaque.
+type cursor struct {
+	Sort     string            `json:"s"`
+	Values   []json.RawMessage `json:"v,omitempty"`
+	ID       int64             `json:"i"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+// encodeCursor returns the opaque cursor for movie under the given sort and its keys.
+func encodeCursor(sort string, keys []sortKey, movie *Movie, backward bool) string {
+	c := cursor{Sort: sort, ID: movie.ID, Backward: backward}
+
+	for _, key := range keys {
+		if key.column == "id" {
+			continue
+		}
+		value, err := json.Marshal(sortValue(movie, key.column))
+		if err != nil {
+			panic(err) // sort values are plain strings and numbers
+		}
+		c.Values = append(c.Values, value)
+	}
+
+	js, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// decodeCursor parses an opaque cursor, ensuring it was issued for sort.
+func decodeCursor(s, sort string, keys []sortKey) (*cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.Sort != sort || c.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	if _, err := c.pivot(keys); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// pivot returns a Movie holding the cursor's id and sort values, suitable for comparing against other rows.
+func (c cursor) pivot(keys []sortKey) (*Movie, error) {
+	movie := &Movie{ID: c.ID}
+
+	values := c.Values
+	for _, key := range keys {
+		if key.column == "id" {
+			continue
+		}
+		if len(values) == 0 {
+			return nil, ErrInvalidCursor
+		}
+		if err := setSortValue(movie, key.column, values[0]); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values = values[1:]
+	}
+
+	if len(values) > 0 {
+		return nil, ErrInvalidCursor
+	}
+	return movie, nil
+}
+
+// setSortValue decodes the value of the named sort column into movie.
+func setSortValue(movie *Movie, column string, value json.RawMessage) error {
+	switch column {
+	case "title":
+		return json.Unmarshal(value, &movie.Title)
+	case "year":
+		return json.Unmarshal(value, &movie.Year)
+	case "runtime":
+		var runtime int32
+		err := json.Unmarshal(value, &runtime)
+		movie.Runtime = Runtime(runtime)
+		return err
+	case "relevance":
+		return json.Unmarshal(value, &movie.Rank)
+	case "similarity":
+		return json.Unmarshal(value, &movie.Similarity)
+	case "rating":
+		return json.Unmarshal(value, &movie.Rating)
+	default:
+		return ErrInvalidCursor
+	}
+}
+
+// sortValue returns the value of the named sort column for movie.
+func sortValue(movie *Movie, column string) interface{} {
+	switch column {
+	case "title":
+		return movie.Title
+	case "year":
+		return movie.Year
+	case "runtime":
+		return int32(movie.Runtime)
+	case "relevance":
+		return movie.Rank
+	case "similarity":
+		return movie.Similarity
+	case "rating":
+		return movie.Rating
+	default:
+		return movie.ID
+	}
+}
+
+// keysetCondition builds the WHERE clause selecting rows beyond the cursor row, for a query ordered by keys. expr
+// gives the expression each column is sorted by and args the placeholders of the cursor's value for each key. A row
+// is beyond the cursor when it equals the cursor on some leading keys and is past it on the next, as the keys end
+// with the unique id this orders every row. The sort columns are never NULL, so plain comparisons suffice.
+func keysetCondition(keys []sortKey, expr func(column string) string, backward bool, args []string) string {
+	var terms, equal []string
+	for i, key := range keys {
+		op := ">"
+		if key.descending != backward {
+			op = "<"
+		}
+
+		term := fmt.Sprintf("%s %s %s", expr(key.column), op, args[i])
+		if len(equal) > 0 {
+			term = "(" + strings.Join(equal, " AND ") + " AND " + term + ")"
+		}
+		terms = append(terms, term)
+		equal = append(equal, fmt.Sprintf("%s = %s", expr(key.column), args[i]))
+	}
+
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
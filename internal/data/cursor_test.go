@@ -0,0 +1,89 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	keys := []sortKey{{"year", true}, {"title", false}, {"rating", false}, {"id", false}}
+	movie := &Movie{ID: 7, Title: "Moana", Year: 2016, Rating: 7.5}
+
+	s := encodeCursor("-year,title,rating", keys, movie, true)
+
+	c, err := decodeCursor(s, "-year,title,rating", keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Backward {
+		t.Error("got a forward cursor; want backward")
+	}
+
+	pivot, err := c.pivot(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pivot.ID != movie.ID || pivot.Year != movie.Year || pivot.Title != movie.Title || pivot.Rating != movie.Rating {
+		t.Errorf("got pivot %+v; want the sort values of %+v", pivot, movie)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	keys := []sortKey{{"title", false}, {"id", false}}
+	valid := encodeCursor("title", keys, &Movie{ID: 3, Title: "Up"}, false)
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		keys   []sortKey
+	}{
+		{"not base64", "not a cursor!", "title", keys},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("{")), "title", keys},
+		{"other sort", valid, "-title", []sortKey{{"title", true}, {"id", false}}},
+		{"missing id", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"title","v":["Up"]}`)), "title", keys},
+		{"too few values", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"title","i":3}`)), "title", keys},
+		{"too many values", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"title","v":["Up",1],"i":3}`)), "title", keys},
+		{"wrong type", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"year","v":["Up"],"i":3}`)), "year", []sortKey{{"year", false}, {"id", false}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := decodeCursor(tt.cursor, tt.sort, tt.keys); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: got error %v; want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	expr := func(column string) string { return column }
+
+	tests := []struct {
+		name     string
+		keys     []sortKey
+		backward bool
+		want     string
+	}{
+		{"id", []sortKey{{"id", false}}, false, "id > $1"},
+		{"id backward", []sortKey{{"id", false}}, true, "id < $1"},
+		{
+			"two keys",
+			[]sortKey{{"year", true}, {"id", false}},
+			false,
+			"(year < $1 OR (year = $1 AND id > $2))",
+		},
+		{
+			"three keys backward",
+			[]sortKey{{"year", true}, {"title", false}, {"id", false}},
+			true,
+			"(year > $1 OR (year = $1 AND title < $2) OR (year = $1 AND title = $2 AND id < $3))",
+		},
+	}
+
+	for _, tt := range tests {
+		args := []string{"$1", "$2", "$3"}[:len(tt.keys)]
+		if got := keysetCondition(tt.keys, expr, tt.backward, args); got != tt.want {
+			t.Errorf("%s: got %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// exportFetchSize is the number of rows fetched from the export cursor at a time.
+const exportFetchSize = 500
+
+// Export calls fn with every movie matching criteria in the order given by filters; the page and cursor of filters
+// are ignored. Rows are read through a server-side cursor a batch at a time, so memory use does not grow with the
+// number of matches. The export stops at the first error returned by fn, or when ctx is cancelled.
+func (m MovieModel) Export(ctx context.Context, criteria MovieCriteria, filters Filters, fn func(*Movie) error) error {
+	var q filterQuery
+	search, err := criteria.apply(&q)
+	if err != nil {
+		return err
+	}
+
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return err
+	}
+	order := orderByKeys(keys, func(column string) string { return sortExpression(column, search) })
+
+	// A cursor must be declared within a transaction, which is read only as the export never writes.
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	declare := fmt.Sprintf(`DECLARE movies_export NO SCROLL CURSOR FOR
+	SELECT id, created_at, title, year, runtime, genres, version, %s, %s, rating_avg, rating_count, poster, poster_sizes,
+	       external_ids
+	FROM movies
+	WHERE %s
+	ORDER BY %s`,
+		search.rank, search.headline, q.clause(), order)
+
+	if _, err = tx.ExecContext(ctx, declare, q.args...); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM movies_export", exportFetchSize)
+
+	for {
+		fetched, err := m.exportBatch(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if fetched < exportFetchSize {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
+
+// exportBatch fetches the next batch of rows from the export cursor and passes each to fn, returning the number of
+// rows fetched.
+func (m MovieModel) exportBatch(ctx context.Context, tx *sql.Tx, fetch string, fn func(*Movie) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.Rank,
+			&movie.Headline,
+			&movie.Rating,
+			&movie.RatingCount,
+			&movie.Poster,
+			pq.Array(&movie.PosterSizes),
+			&movie.ExternalIDs)
+		if err != nil {
+			return fetched, err
+		}
+		fetched++
+
+		if err := fn(&movie); err != nil {
+			return fetched, err
+		}
+	}
+
+	return fetched, rows.Err()
+}
@@ -0,0 +1,162 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrDuplicateExternalID is returned when a movie is saved with an external ID already held by another movie.
+var ErrDuplicateExternalID = errors.New("duplicate external id")
+
+// ExternalSources maps each catalogue a movie may be linked to onto the format of its IDs. Every source has a unique
+// index on movies, so adding one here also needs a migration.
+var ExternalSources = map[string]*regexp.Regexp{
+	"imdb":     regexp.MustCompile(`^tt[0-9]{7,10}$`),
+	"tmdb":     regexp.MustCompile(`^[1-9][0-9]{0,9}$`),
+	"wikidata": regexp.MustCompile(`^Q[1-9][0-9]*$`),
+}
+
+// DuplicateTitleSimilarity is the trigram similarity, between 0 and 1, at which the titles of two movies from the same
+// year are taken to be the same film by FindDuplicates.
+const DuplicateTitleSimilarity = 0.6
+
+// ExternalIDs holds the IDs of a movie in other catalogues keyed by source, such as {"imdb": "tt0468569"}. It is
+// stored as a jsonb object in the external_ids column.
+type ExternalIDs map[string]string
+
+// Value implements driver.Valuer, storing a nil map as an empty object.
+func (ids ExternalIDs) Value() (driver.Value, error) {
+	if ids == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]string(ids))
+}
+
+// Scan implements sql.Scanner. An empty object is scanned as a nil map, so movies without external IDs compare equal
+// however they were loaded.
+func (ids *ExternalIDs) Scan(src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into ExternalIDs", src)
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	*ids = nil
+	if len(m) > 0 {
+		*ids = m
+	}
+	return nil
+}
+
+// copyExternalIDs returns a copy of ids, nil when there are none.
+func copyExternalIDs(ids ExternalIDs) ExternalIDs {
+	if len(ids) == 0 {
+		return nil
+	}
+	c := make(ExternalIDs, len(ids))
+	for source, id := range ids {
+		c[source] = id
+	}
+	return c
+}
+
+// externalSourceNames lists the keys of ExternalSources in order, for messages.
+func externalSourceNames() []string {
+	names := make([]string, 0, len(ExternalSources))
+	for source := range ExternalSources {
+		names = append(names, source)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateExternalIDs checks that each ID belongs to a known source and has that source's format.
+func ValidateExternalIDs(v *validator.Validator, ids ExternalIDs) {
+	sources := make([]string, 0, len(ids))
+	for source := range ids {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		rx, ok := ExternalSources[source]
+		if !ok {
+			v.AddError("external_ids", "must only contain IDs from "+strings.Join(externalSourceNames(), ", "))
+			continue
+		}
+		v.Check(validator.Matches(ids[source], rx), "external_ids", fmt.Sprintf("must contain a valid %s ID", source))
+	}
+}
+
+// FindDuplicates returns up to five live movies from the same year as movie whose titles are near-identical to its
+// title, most similar first. Titles are compared with pg_trgm's similarity, ignoring case and punctuation.
+func (m MovieModel) FindDuplicates(movie *Movie) ([]*Movie, error) {
+	query := `
+	SELECT id, created_at, title, year, runtime, genres, version, rating_avg, rating_count, poster, poster_sizes,
+	       external_ids
+	FROM movies
+	WHERE year = $2 AND deleted_at IS NULL AND title % $1 AND similarity(title, $1) >= $3
+	ORDER BY similarity(title, $1) DESC, id
+	LIMIT 5`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movie.Title, movie.Year, DuplicateTitleSimilarity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.Rating,
+			&movie.RatingCount,
+			&movie.Poster,
+			pq.Array(&movie.PosterSizes),
+			&movie.ExternalIDs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+
+	return movies, rows.Err()
+}
+
+// externalIDError translates a unique violation on one of the external ID indexes into ErrDuplicateExternalID,
+// returning any other error unchanged.
+func externalIDError(err error) error {
+	if err != nil && strings.HasPrefix(err.Error(), `pq: duplicate key value violates unique constraint "movies_external_`) {
+		return ErrDuplicateExternalID
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"movieDB/internal/validator"
+	"strings"
+	"time"
+)
+
+// FacetSafeList holds the facets which may be requested alongside a movie listing.
+var FacetSafeList = []string{"genres", "decade", "runtime"}
+
+// runtimeBuckets divides runtimes into the ranges reported by the runtime facet. Bounds are inclusive, a max of 0
+// leaves the bucket open-ended.
+var runtimeBuckets = []struct {
+	label    string
+	min, max int32
+}{
+	{"0-89", 0, 89},
+	{"90-119", 90, 119},
+	{"120-149", 120, 149},
+	{"150+", 150, 0},
+}
+
+// FacetCount is the number of matching movies sharing a single facet value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets maps a facet name to the counts for each of its values.
+type Facets map[string][]FacetCount
+
+// Facets counts the movies matching criteria by each of the named facets. Counts cover every match, not just a single
+// page of GetAll.
+func (m MovieModel) Facets(criteria MovieCriteria, names []string) (Facets, error) {
+	var q filterQuery
+	if _, err := criteria.apply(&q); err != nil {
+		return nil, err
+	}
+
+	facets := make(Facets, len(names))
+
+	for _, name := range names {
+		var query string
+		switch name {
+		case "genres":
+			query = fmt.Sprintf(`SELECT genre, count(*)
+			FROM movies, unnest(genres) AS genre
+			WHERE %s
+			GROUP BY genre
+			ORDER BY count(*) DESC, genre`, q.clause())
+		case "decade":
+			query = fmt.Sprintf(`SELECT (year / 10 * 10) || 's', count(*)
+			FROM movies
+			WHERE %s
+			GROUP BY year / 10
+			ORDER BY year / 10`, q.clause())
+		case "runtime":
+			query = fmt.Sprintf(`SELECT %s AS bucket, count(*)
+			FROM movies
+			WHERE %s
+			GROUP BY bucket
+			ORDER BY min(runtime)`, runtimeBucketExpression(), q.clause())
+		default:
+			return nil, fmt.Errorf("unknown facet %q", name)
+		}
+
+		counts, err := m.facetCounts(query, q.args)
+		if err != nil {
+			return nil, err
+		}
+		facets[name] = counts
+	}
+
+	return facets, nil
+}
+
+// facetCounts runs a query returning (value, count) rows.
+func (m MovieModel) facetCounts(query string, args []interface{}) ([]FacetCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []FacetCount{}
+	for rows.Next() {
+		var count FacetCount
+		if err := rows.Scan(&count.Value, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// runtimeBucketExpression builds a CASE expression labelling runtime with its bucket from runtimeBuckets.
+func runtimeBucketExpression() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, bucket := range runtimeBuckets {
+		if bucket.max == 0 {
+			fmt.Fprintf(&b, " WHEN runtime >= %d THEN '%s'", bucket.min, bucket.label)
+		} else {
+			fmt.Fprintf(&b, " WHEN runtime BETWEEN %d AND %d THEN '%s'", bucket.min, bucket.max, bucket.label)
+		}
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+// runtimeBucket returns the label of the bucket holding runtime.
+func runtimeBucket(runtime Runtime) string {
+	for _, bucket := range runtimeBuckets {
+		if int32(runtime) >= bucket.min && (bucket.max == 0 || int32(runtime) <= bucket.max) {
+			return bucket.label
+		}
+	}
+	return ""
+}
+
+// ValidateFacets checks every requested facet is in FacetSafeList.
+func ValidateFacets(v *validator.Validator, names []string) {
+	for _, name := range names {
+		v.Check(validator.In(name, FacetSafeList...), "facets", "invalid facet "+name)
+	}
+	v.Check(validator.Unique(names), "facets", "must not contain duplicate values")
+}
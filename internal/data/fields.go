@@ -0,0 +1,182 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"strings"
+)
+
+// MovieFields lists the fields of a movie which a client may limit a response to, in the order they are written.
+var MovieFields = []string{
+	"id", "title", "title_locale", "year", "runtime", "genres", "external_ids", "version", "rank", "headline",
+	"similarity", "deleted_at", "rating", "rating_count", "posters",
+}
+
+// MovieIncludes lists the related resources which may be embedded in a movie, see Movie.Credits, Movie.Ratings and
+// Movie.Titles.
+var MovieIncludes = []string{"credits", "ratings", "titles"}
+
+// IncludedRatings is the number of ratings embedded in each movie, the most recent first. The rest are listed by
+// GET /v1/movies/:id/ratings.
+const IncludedRatings = 10
+
+// ValidateFields checks that each field is one of MovieFields.
+func ValidateFields(v *validator.Validator, fields []string) {
+	for _, field := range fields {
+		v.Check(validator.In(field, MovieFields...), "fields", "invalid field "+field)
+	}
+	v.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+}
+
+// ValidateIncludes checks that each include is one of MovieIncludes.
+func ValidateIncludes(v *validator.Validator, includes []string) {
+	for _, include := range includes {
+		v.Check(validator.In(include, MovieIncludes...), "include", "invalid include "+include)
+	}
+	v.Check(validator.Unique(includes), "include", "must not contain duplicate values")
+}
+
+// movieColumn is a column, or expression, selected from the movies table.
+type movieColumn struct {
+	expr   string
+	fields []string                 // the fields written from the column
+	always bool                     // selected whichever fields are requested
+	dest   func(*Movie) interface{} // where the column is scanned into
+}
+
+// movieColumns holds the columns making up a movie, in the order they are selected.
+var movieColumns = []movieColumn{
+	{expr: "id", always: true, dest: func(m *Movie) interface{} { return &m.ID }},
+	{expr: "created_at", dest: func(m *Movie) interface{} { return &m.CreatedAt }},
+	{expr: "title", fields: []string{"title"}, dest: func(m *Movie) interface{} { return &m.Title }},
+	{expr: "year", fields: []string{"year"}, dest: func(m *Movie) interface{} { return &m.Year }},
+	{expr: "runtime", fields: []string{"runtime"}, dest: func(m *Movie) interface{} { return &m.Runtime }},
+	{expr: "genres", fields: []string{"genres"}, dest: func(m *Movie) interface{} { return pq.Array(&m.Genres) }},
+	// The version is always read as the ETag of a movie is derived from it.
+	{expr: "version", always: true, dest: func(m *Movie) interface{} { return &m.Version }},
+	{expr: "rating_avg", fields: []string{"rating"}, dest: func(m *Movie) interface{} { return &m.Rating }},
+	{expr: "rating_count", fields: []string{"rating_count"}, dest: func(m *Movie) interface{} { return &m.RatingCount }},
+	{expr: "poster", fields: []string{"posters"}, dest: func(m *Movie) interface{} { return &m.Poster }},
+	{expr: "poster_sizes", fields: []string{"posters"}, dest: func(m *Movie) interface{} { return pq.Array(&m.PosterSizes) }},
+	{expr: "external_ids", fields: []string{"external_ids"}, dest: func(m *Movie) interface{} { return &m.ExternalIDs }},
+}
+
+// searchColumns returns the columns only selected by GetAll, the expressions scoring a title search or similar
+// movie along with deleted_at for listing the trash.
+func searchColumns(search titleSearch) []movieColumn {
+	return []movieColumn{
+		{expr: search.rank, fields: []string{"rank"}, dest: func(m *Movie) interface{} { return &m.Rank }},
+		{expr: search.headline, fields: []string{"headline"}, dest: func(m *Movie) interface{} { return &m.Headline }},
+		{expr: search.similarity, fields: []string{"similarity"}, dest: func(m *Movie) interface{} { return &m.Similarity }},
+		{expr: "deleted_at", fields: []string{"deleted_at"}, dest: func(m *Movie) interface{} { return &m.DeletedAt }},
+	}
+}
+
+// selectColumns returns the select list of the columns needed to write the given fields, every column when fields
+// is empty, along with a function returning the destinations to scan a row into.
+func selectColumns(columns []movieColumn, fields []string) (string, func(*Movie) []interface{}) {
+	var selected []movieColumn
+	for _, column := range columns {
+		if len(fields) == 0 || column.always || containsAny(column.fields, fields) {
+			selected = append(selected, column)
+		}
+	}
+
+	exprs := make([]string, len(selected))
+	for i, column := range selected {
+		exprs[i] = column.expr
+	}
+
+	return strings.Join(exprs, ", "), func(movie *Movie) []interface{} {
+		dest := make([]interface{}, len(selected))
+		for i, column := range selected {
+			dest[i] = column.dest(movie)
+		}
+		return dest
+	}
+}
+
+// sortField returns the field holding the value a movie is sorted by for the named sort column.
+func sortField(column string) string {
+	if column == "relevance" {
+		return "rank"
+	}
+	return column
+}
+
+// MarshalJSON writes the movie with its runtime in RuntimeFormat, limited to Fields when they are set. As the runtime
+// is written after the other fields to do so, movies in the default format are written as they are. Included
+// resources are always written, they are only set when asked for.
+func (m Movie) MarshalJSON() ([]byte, error) {
+	type movie Movie
+
+	var js []byte
+	var err error
+	if m.RuntimeFormat == "" || m.RuntimeFormat == RuntimeFormatMins {
+		js, err = json.Marshal(movie(m))
+	} else {
+		var runtime json.RawMessage
+		if m.Runtime != 0 {
+			runtime, err = m.Runtime.MarshalFormat(m.RuntimeFormat)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		js, err = json.Marshal(struct {
+			movie
+			Runtime json.RawMessage `json:"runtime,omitempty"`
+		}{movie(m), runtime})
+	}
+
+	if err != nil || len(m.Fields) == 0 {
+		return js, err
+	}
+
+	return filterObject(js, func(name string) bool {
+		return validator.In(name, m.Fields...) || validator.In(name, MovieIncludes...)
+	})
+}
+
+// filterObject rewrites the JSON object js with only the members for which keep returns true, in their original
+// order.
+func filterObject(js []byte, keep func(name string) bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := token.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if !keep(name) {
+			continue
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"movieDB/internal/validator"
 	"strings"
@@ -12,15 +14,19 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+	Cursor       string // when set, keyset pagination is used in place of Page
+	IncludeTotal bool   // count the matching records, this costs a second scan of the table
 }
 
 // Metadata holds Additional data provided to client indicating Filters
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -37,22 +43,144 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	}
 }
 
-//sortColumn indicates the Column used for sorting within a SQL query.
-func (f Filters) sortColumn() string {
-	for _, safeValue := range f.SortSafeList {
-		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+// paginate trims rows fetched with limit()+1 down to a single page and builds the Metadata for it, including the
+// cursors for the neighbouring pages. Rows are expected in query order, which for a backward cursor is the reverse of
+// the requested sort.
+func paginate(movies []*Movie, totalRecords int, f Filters, keys []sortKey, after *cursor) ([]*Movie, Metadata) {
+	hasMore := len(movies) > f.limit()
+	if hasMore {
+		movies = movies[:f.limit()]
+	}
+
+	backward := after != nil && after.Backward
+	if backward {
+		for i, j := 0, len(movies)-1; i < j; i, j = i+1, j-1 {
+			movies[i], movies[j] = movies[j], movies[i]
 		}
 	}
-	panic("unsafe sort parameter" + f.Sort)
+
+	var metadata Metadata
+	switch {
+	case after != nil:
+		metadata = Metadata{PageSize: f.PageSize, TotalRecords: totalRecords}
+	case f.IncludeTotal:
+		metadata = calculateMetadata(totalRecords, f.Page, f.PageSize)
+	default:
+		metadata = Metadata{CurrentPage: f.Page, PageSize: f.PageSize, FirstPage: 1}
+	}
+
+	if len(movies) == 0 {
+		return movies, metadata
+	}
+
+	if (backward && hasMore) || (!backward && (after != nil || f.Page > 1)) {
+		metadata.PrevCursor = encodeCursor(f.Sort, keys, movies[0], true)
+	}
+	if backward || hasMore {
+		metadata.NextCursor = encodeCursor(f.Sort, keys, movies[len(movies)-1], false)
+	}
+
+	return movies, metadata
 }
 
-// sortDirection defaults to Ascending unless "-" is passed as a query parameter.
-func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+// ErrInvalidSort is returned when a sort names a column outside the safelist.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// MaxSortKeys is the most comma separated keys a sort may be made of.
+const MaxSortKeys = 3
+
+// sortKey is a single key of a sort, a column of the safelist and the direction it is sorted in.
+type sortKey struct {
+	column     string
+	descending bool
+}
+
+// direction returns the ORDER BY direction of the key. Nulls are placed as the greatest value in either direction, as
+// they are within a btree index, so a sort reads the same order whichever way an index is scanned.
+func (k sortKey) direction() string {
+	if k.descending {
+		return "DESC NULLS FIRST"
+	}
+	return "ASC NULLS LAST"
+}
+
+// sortKeys parses the sort into its keys, followed by id ascending as the tie-breaker unless the sort includes id.
+// Each key is a column of the SortSafeList, ascending unless prefixed by "-". Relevance and similarity are the
+// exceptions, the best match has the highest score so they sort in descending order.
+func (f Filters) sortKeys() ([]sortKey, error) {
+	var keys []sortKey
+	tieBreaker := true
+
+	for _, value := range strings.Split(f.Sort, ",") {
+		value = strings.TrimSpace(value)
+		key := sortKey{column: strings.TrimPrefix(value, "-"), descending: strings.HasPrefix(value, "-")}
+		if key.column == "" || !validator.In(key.column, f.SortSafeList...) {
+			return nil, ErrInvalidSort
+		}
+
+		if key.column == "relevance" || key.column == "similarity" {
+			key.descending = !key.descending
+		}
+		if key.column == "id" {
+			tieBreaker = false
+		}
+		keys = append(keys, key)
 	}
-	return "ASC"
+
+	if tieBreaker {
+		keys = append(keys, sortKey{column: "id"})
+	}
+	return keys, nil
+}
+
+// orderBy returns the ORDER BY list of the sort, for tables whose sort columns are named as they are in the safelist.
+func (f Filters) orderBy() (string, error) {
+	keys, err := f.sortKeys()
+	if err != nil {
+		return "", err
+	}
+	return orderByKeys(keys, func(column string) string { return column }), nil
+}
+
+// orderByKeys returns the ORDER BY list of keys, expr giving the expression each column is sorted by.
+func orderByKeys(keys []sortKey, expr func(column string) string) string {
+	terms := make([]string, len(keys))
+	for i, key := range keys {
+		terms[i] = expr(key.column) + " " + key.direction()
+	}
+	return strings.Join(terms, ", ")
+}
+
+// reverseKeys returns keys with the direction of each flipped, for walking backwards from a cursor.
+func reverseKeys(keys []sortKey) []sortKey {
+	reversed := make([]sortKey, len(keys))
+	for i, key := range keys {
+		reversed[i] = sortKey{column: key.column, descending: !key.descending}
+	}
+	return reversed
+}
+
+// compareKeys orders two rows by keys, matching the ORDER BY of orderByKeys. compare returns -1, 0 or +1 as the rows
+// compare in ascending order of a single column.
+func compareKeys(keys []sortKey, compare func(column string) int) int {
+	for _, key := range keys {
+		c := compare(key.column)
+		if key.descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// cursor decodes the keyset cursor, returning nil when paging by page number.
+func (f Filters) cursor(keys []sortKey) (*cursor, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+	return decodeCursor(f.Cursor, f.Sort, keys)
 }
 
 // limit will apply a page size limit. e.g. 5 movies per page.
@@ -77,5 +205,32 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
-	v.Check(validator.In(f.Sort, f.SortSafeList...), "sort", "invalid sort value")
+	ValidateSort(v, f)
+
+	if f.Cursor != "" {
+		v.Check(f.Page == 1, "page", "must not be combined with cursor")
+		// A cursor can only be checked against a valid sort, an invalid sort is reported by ValidateSort.
+		if keys, err := f.sortKeys(); err == nil {
+			_, err = f.cursor(keys)
+			v.Check(err == nil, "cursor", "invalid cursor for this sort")
+		}
+	}
+}
+
+// ValidateSort checks that the sort is a comma separated list of up to MaxSortKeys columns of the SortSafeList, each
+// optionally prefixed by "-", with no column repeated.
+func ValidateSort(v *validator.Validator, f Filters) {
+	var columns []string
+	for _, value := range strings.Split(f.Sort, ",") {
+		column := strings.TrimPrefix(strings.TrimSpace(value), "-")
+		if column == "" {
+			v.AddError("sort", "must not contain empty values")
+			continue
+		}
+		v.Check(validator.In(column, f.SortSafeList...), "sort", "invalid sort value "+column)
+		columns = append(columns, column)
+	}
+
+	v.Check(validator.Unique(columns), "sort", "must not contain duplicate columns")
+	v.Check(len(columns) <= MaxSortKeys, "sort", fmt.Sprintf("must not contain more than %d columns", MaxSortKeys))
 }
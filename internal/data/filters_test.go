@@ -0,0 +1,130 @@
+package data
+
+import (
+	"errors"
+	"movieDB/internal/validator"
+	"reflect"
+	"testing"
+)
+
+func TestSortKeys(t *testing.T) {
+	safeList := []string{"id", "title", "year", "relevance"}
+
+	tests := []struct {
+		sort    string
+		want    []sortKey
+		wantErr error
+	}{
+		{"id", []sortKey{{"id", false}}, nil},
+		{"-id", []sortKey{{"id", true}}, nil},
+		{"title", []sortKey{{"title", false}, {"id", false}}, nil},
+		{"-year, title", []sortKey{{"year", true}, {"title", false}, {"id", false}}, nil},
+		{"year,-id", []sortKey{{"year", false}, {"id", true}}, nil},
+		{"relevance", []sortKey{{"relevance", true}, {"id", false}}, nil},
+		{"-relevance", []sortKey{{"relevance", false}, {"id", false}}, nil},
+		{"runtime", nil, ErrInvalidSort},
+		{"title,", nil, ErrInvalidSort},
+		{"", nil, ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		f := Filters{Sort: tt.sort, SortSafeList: safeList}
+		got, err := f.sortKeys()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("sortKeys(%q): got error %v; want %v", tt.sort, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sortKeys(%q) = %v; want %v", tt.sort, got, tt.want)
+		}
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	f := Filters{Sort: "-year,title", SortSafeList: []string{"id", "title", "year"}}
+
+	got, err := f.orderBy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "year DESC NULLS FIRST, title ASC NULLS LAST, id ASC NULLS LAST"
+	if got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestCompareKeys(t *testing.T) {
+	keys := []sortKey{{"year", true}, {"id", false}}
+	values := map[string][2]int64{"year": {2000, 2000}, "id": {1, 2}}
+
+	compare := func(column string) int {
+		return compareInt64(values[column][0], values[column][1])
+	}
+
+	if got := compareKeys(keys, compare); got != -1 {
+		t.Errorf("equal years: got %d; want -1 from the id tie-breaker", got)
+	}
+
+	values["year"] = [2]int64{1999, 2000}
+	if got := compareKeys(keys, compare); got != 1 {
+		t.Errorf("descending year: got %d; want 1", got)
+	}
+
+	if got := compareKeys(reverseKeys(keys), compare); got != -1 {
+		t.Errorf("reversed keys: got %d; want -1", got)
+	}
+}
+
+func TestValidateFilters(t *testing.T) {
+	safeList := []string{"id", "title", "year", "runtime"}
+	cursor := encodeCursor("title", []sortKey{{"title", false}, {"id", false}}, &Movie{ID: 3, Title: "Up"}, false)
+
+	tests := []struct {
+		name      string
+		filters   Filters
+		wantError string // field expected in the errors, none when empty
+	}{
+		{"valid", Filters{Page: 1, PageSize: 20, Sort: "-year,title"}, ""},
+		{"page zero", Filters{Page: 0, PageSize: 20, Sort: "id"}, "page"},
+		{"page size too large", Filters{Page: 1, PageSize: 101, Sort: "id"}, "page_size"},
+		{"unknown column", Filters{Page: 1, PageSize: 20, Sort: "rating"}, "sort"},
+		{"empty key", Filters{Page: 1, PageSize: 20, Sort: "title,,year"}, "sort"},
+		{"duplicate column", Filters{Page: 1, PageSize: 20, Sort: "title,-title"}, "sort"},
+		{"too many keys", Filters{Page: 1, PageSize: 20, Sort: "title,year,runtime,id"}, "sort"},
+		{"cursor", Filters{Page: 1, PageSize: 20, Sort: "title", Cursor: cursor}, ""},
+		{"cursor with page", Filters{Page: 2, PageSize: 20, Sort: "title", Cursor: cursor}, "page"},
+		{"cursor for another sort", Filters{Page: 1, PageSize: 20, Sort: "year", Cursor: cursor}, "cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.SortSafeList = safeList
+
+			v := validator.New()
+			ValidateFilters(v, tt.filters)
+
+			if tt.wantError == "" {
+				if !v.Valid() {
+					t.Errorf("got errors %v; want none", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.wantError]; !ok {
+				t.Errorf("got errors %v; want one for %s", v.Errors, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCalculateMetadata(t *testing.T) {
+	got := calculateMetadata(41, 2, 20)
+	want := Metadata{CurrentPage: 2, PageSize: 20, FirstPage: 1, LastPage: 3, TotalRecords: 41}
+	if got != want {
+		t.Errorf("got %+v; want %+v", got, want)
+	}
+
+	if got := calculateMetadata(0, 1, 20); got != (Metadata{}) {
+		t.Errorf("got %+v for no records; want empty metadata", got)
+	}
+}
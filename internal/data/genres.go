@@ -0,0 +1,340 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDuplicateGenre is returned when a genre's slug or one of its aliases is already used by another genre.
+	ErrDuplicateGenre = errors.New("duplicate genre")
+	// ErrGenreInUse is returned when deleting a genre which movies, including those in the trash, still carry.
+	ErrGenreInUse = errors.New("genre in use")
+	// ErrTooManyAliases is returned when renaming a genre whose aliases leave no room for the old slug.
+	ErrTooManyAliases = errors.New("too many genre aliases")
+)
+
+// MaxGenreAliases is the number of aliases a genre may have, including the old slugs kept when it is renamed.
+const MaxGenreAliases = 20
+
+// Genre is an entry of the managed genre taxonomy. Movies carry genres by slug; any of a genre's aliases given in
+// place of the slug is resolved to it by GenreIndex.
+type Genre struct {
+	ID      int64    `json:"id"`
+	Slug    string   `json:"slug"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	Version int32    `json:"version"`
+}
+
+// NormalizeGenre reduces a genre to the form used for slugs and aliases: lower case words of letters and digits
+// joined by hyphens, so "Sci-Fi", "sci fi" and "SCI_FI" all become "sci-fi". Migration 000015 uses the same rule.
+func NormalizeGenre(s string) string {
+	return strings.Join(simpleLexemes(s), "-")
+}
+
+// GenreIndex maps the normalised slugs and aliases of the taxonomy to their canonical slugs.
+type GenreIndex map[string]string
+
+// NewGenreIndex builds the index of the given genres.
+func NewGenreIndex(genres []*Genre) GenreIndex {
+	index := make(GenreIndex)
+	for _, genre := range genres {
+		index[genre.Slug] = genre.Slug
+		for _, alias := range genre.Aliases {
+			index[alias] = genre.Slug
+		}
+	}
+	return index
+}
+
+// Resolve returns the canonical slugs of names in order, along with the names which match no genre.
+func (ix GenreIndex) Resolve(names []string) (slugs []string, unknown []string) {
+	slugs = make([]string, 0, len(names))
+	for _, name := range names {
+		slug, ok := ix[NormalizeGenre(name)]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		slugs = append(slugs, slug)
+	}
+	return slugs, unknown
+}
+
+// GenreModel holds the database pool for the genres table.
+type GenreModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a genre to the taxonomy, returning ErrDuplicateGenre if its slug or an alias is already taken.
+func (m GenreModel) Insert(genre *Genre) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.lockTaxonomy(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.checkUnique(ctx, tx, genre); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO genres (slug, name, aliases)
+	VALUES ($1, $2, $3)
+	RETURNING id, version`
+
+	err = tx.QueryRowContext(ctx, query, genre.Slug, genre.Name, pq.Array(genre.Aliases)).Scan(&genre.ID, &genre.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get retrieves a single genre.
+func (m GenreModel) Get(id int64) (*Genre, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, slug, name, aliases, version
+	FROM genres
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanGenre(m.DB.QueryRowContext(ctx, query, id))
+}
+
+// GetAll returns the whole taxonomy ordered by slug. It is small enough not to need paging.
+func (m GenreModel) GetAll() ([]*Genre, error) {
+	query := `
+	SELECT id, slug, name, aliases, version
+	FROM genres
+	ORDER BY slug`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*Genre{}
+	for rows.Next() {
+		genre, err := scanGenre(rows)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+
+	return genres, rows.Err()
+}
+
+// Update saves a genre provided the version has not changed since it was read, otherwise ErrEditConflict. When the
+// slug changes every movie carrying the old slug, including those in the trash, is moved to the new one and the old
+// slug is kept as an alias so existing clients and links continue to resolve. Each movie moved is saved as a new
+// version, with its revision attributed to userID, so clients holding the old version see the change. A genre which
+// already has MaxGenreAliases cannot be renamed, ErrTooManyAliases is returned.
+func (m GenreModel) Update(genre *Genre, userID *int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.lockTaxonomy(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldSlug string
+	err = tx.QueryRowContext(ctx, `SELECT slug FROM genres WHERE id = $1 AND version = $2`, genre.ID, genre.Version).Scan(&oldSlug)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if oldSlug != genre.Slug && !validator.In(oldSlug, genre.Aliases...) {
+		if len(genre.Aliases) >= MaxGenreAliases {
+			return ErrTooManyAliases
+		}
+		genre.Aliases = append(genre.Aliases, oldSlug)
+	}
+
+	if err := m.checkUnique(ctx, tx, genre); err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE genres
+	SET slug = $1, name = $2, aliases = $3, version = version + 1
+	WHERE id = $4
+	RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, genre.Slug, genre.Name, pq.Array(genre.Aliases), genre.ID).Scan(&genre.Version)
+	if err != nil {
+		return err
+	}
+
+	if oldSlug != genre.Slug {
+		rows, err := tx.QueryContext(ctx, `
+		UPDATE movies
+		SET genres = array_replace(genres, $1::text, $2::text), version = version + 1
+		WHERE genres @> ARRAY[$1::text]
+		RETURNING id, title, year, runtime, genres, version, external_ids`, oldSlug, genre.Slug)
+		if err != nil {
+			return err
+		}
+
+		var movies []*Movie
+		for rows.Next() {
+			var movie Movie
+			err = rows.Scan(&movie.ID, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version, &movie.ExternalIDs)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			movies = append(movies, &movie)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		// The revisions are written once the rows are read, as a connection runs one statement at a time.
+		for _, movie := range movies {
+			err = insertRevision(ctx, tx, movie, RevisionUpdate, userID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a genre from the taxonomy, returning ErrGenreInUse while any movie still carries it.
+func (m GenreModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.lockTaxonomy(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `
+	DELETE FROM genres
+	WHERE id = $1
+	RETURNING EXISTS(SELECT 1 FROM movies WHERE genres @> ARRAY[genres.slug])`, id).Scan(&inUse)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	// Rolling back restores the genre.
+	if inUse {
+		return ErrGenreInUse
+	}
+
+	return tx.Commit()
+}
+
+// lockTaxonomy begins a transaction holding a lock which serialises writes to the genres table, as uniqueness of
+// aliases spans rows and cannot be enforced by a constraint.
+func (m GenreModel) lockTaxonomy(ctx context.Context) (*sql.Tx, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE genres IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// checkUnique returns ErrDuplicateGenre if another genre uses the slug or an alias of genre.
+func (m GenreModel) checkUnique(ctx context.Context, tx *sql.Tx, genre *Genre) error {
+	query := `
+	SELECT EXISTS(SELECT 1 FROM genres WHERE id <> $1 AND (slug = ANY($2) OR aliases && $2))`
+
+	names := pq.Array(append([]string{genre.Slug}, genre.Aliases...))
+
+	var taken bool
+	if err := tx.QueryRowContext(ctx, query, genre.ID, names).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicateGenre
+	}
+	return nil
+}
+
+// scanGenre scans a genres row.
+func scanGenre(row interface{ Scan(...interface{}) error }) (*Genre, error) {
+	var genre Genre
+
+	err := row.Scan(&genre.ID, &genre.Slug, &genre.Name, pq.Array(&genre.Aliases), &genre.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if genre.Aliases == nil {
+		genre.Aliases = []string{}
+	}
+	return &genre, nil
+}
+
+// ValidateGenre checks the fields of a genre before it is saved. The slug and aliases must already be normalised
+// with NormalizeGenre.
+func ValidateGenre(v *validator.Validator, genre *Genre) {
+	v.Check(genre.Slug != "", "slug", "must be provided")
+	v.Check(len(genre.Slug) <= 50, "slug", "must not be longer than 50 bytes")
+
+	v.Check(genre.Name != "", "name", "must be provided")
+	v.Check(len(genre.Name) <= 100, "name", "must not be longer than 100 bytes")
+
+	v.Check(len(genre.Aliases) <= MaxGenreAliases, "aliases", fmt.Sprintf("must not contain more than %d aliases", MaxGenreAliases))
+	v.Check(validator.Unique(genre.Aliases), "aliases", "must not contain duplicate values")
+	v.Check(!validator.In(genre.Slug, genre.Aliases...), "aliases", "must not contain the slug")
+	for _, alias := range genre.Aliases {
+		v.Check(alias != "", "aliases", "must contain letters or digits")
+		v.Check(len(alias) <= 50, "aliases", "must not contain an alias longer than 50 bytes")
+	}
+}
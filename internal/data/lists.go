@@ -0,0 +1,520 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"time"
+)
+
+var (
+	// ErrDuplicateListName is returned when a user already has a list with the same name.
+	ErrDuplicateListName = errors.New("duplicate list name")
+	// ErrDuplicateListEntry is returned when a movie is added to a list which already holds it.
+	ErrDuplicateListEntry = errors.New("duplicate list entry")
+)
+
+// List is a named, ordered collection of movies belonging to a single user, such as a watchlist. A list is private
+// unless it has been shared, in which case anyone holding the share token may read it.
+type List struct {
+	ID        int64        `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UserID    int64        `json:"user_id,omitempty"` // left out of the list sent to holders of its share link
+	Name      string       `json:"name"`
+	Shared    bool         `json:"shared"`
+	ShareHash []byte       `json:"-"` // SHA256 hash of the share token, nil while private
+	Version   int32        `json:"version"`
+	Entries   []*ListEntry `json:"entries,omitempty"`
+}
+
+// ListEntry is a movie's place on a list.
+type ListEntry struct {
+	ListID   int64     `json:"-"`
+	Movie    *Movie    `json:"movie"`
+	Position int32     `json:"position"`
+	Notes    string    `json:"notes,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Share gives the list a new share token, replacing any previous one, and returns its plaintext. Only the hash is
+// kept so the token must be handed to the owner now; the list must then be saved with Update.
+func (l *List) Share() (string, error) {
+	plaintext, hash, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	l.ShareHash = hash
+	l.Shared = true
+	return plaintext, nil
+}
+
+// Unshare makes the list private again, invalidating its share token.
+func (l *List) Unshare() {
+	l.ShareHash = nil
+	l.Shared = false
+}
+
+// ListModel holds the database pool for the lists and list_entries tables.
+type ListModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a new list, returning ErrDuplicateListName if the user already has a list of that name.
+func (m ListModel) Insert(list *List) error {
+	query := `
+	INSERT INTO lists (user_id, name, share_hash)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, list.UserID, list.Name, list.ShareHash).Scan(&list.ID, &list.CreatedAt, &list.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "lists_user_id_name_key"`:
+			return ErrDuplicateListName
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get retrieves a single list, without its entries.
+func (m ListModel) Get(id int64) (*List, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, created_at, user_id, name, share_hash, version
+	FROM lists
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanList(m.DB.QueryRowContext(ctx, query, id))
+}
+
+// GetForShareToken retrieves the list shared under the given plaintext token.
+func (m ListModel) GetForShareToken(plaintext string) (*List, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `
+	SELECT id, created_at, user_id, name, share_hash, version
+	FROM lists
+	WHERE share_hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanList(m.DB.QueryRowContext(ctx, query, hash[:]))
+}
+
+// GetAllForUser returns a page of the lists belonging to a user, without their entries.
+func (m ListModel) GetAllForUser(userID int64, filters Filters) ([]*List, Metadata, error) {
+	order, err := filters.orderBy()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, user_id, name, share_hash, version
+	FROM lists
+	WHERE user_id = $1
+	ORDER BY %s
+	LIMIT $2 OFFSET $3`, order)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	lists := []*List{}
+
+	for rows.Next() {
+		list, err := scanList(rows, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		lists = append(lists, list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return lists, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update saves the name and sharing of a list provided the version has not changed since it was read, otherwise
+// ErrEditConflict.
+func (m ListModel) Update(list *List) error {
+	query := `
+	UPDATE lists
+	SET name = $1, share_hash = $2, version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING version`
+
+	args := []interface{}{list.Name, list.ShareHash, list.ID, list.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "lists_user_id_name_key"`:
+			return ErrDuplicateListName
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a list along with its entries.
+func (m ListModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM lists
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetEntries returns the entries of a list in order. Movies in the trash are left out; their entries return with
+// them if they are restored and are removed by the foreign key cascade when they are purged.
+func (m ListModel) GetEntries(listID int64) ([]*ListEntry, error) {
+	query := `
+	SELECT e.position, e.notes, e.added_at,
+	       m.id, m.created_at, m.title, m.year, m.runtime, m.genres, m.version, m.rating_avg, m.rating_count,
+	       m.poster, m.poster_sizes, m.external_ids
+	FROM list_entries e
+	INNER JOIN movies m ON m.id = e.movie_id
+	WHERE e.list_id = $1 AND m.deleted_at IS NULL
+	ORDER BY e.position, e.movie_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*ListEntry{}
+	for rows.Next() {
+		entry := ListEntry{ListID: listID, Movie: &Movie{}}
+		err := rows.Scan(
+			&entry.Position,
+			&entry.Notes,
+			&entry.AddedAt,
+			&entry.Movie.ID,
+			&entry.Movie.CreatedAt,
+			&entry.Movie.Title,
+			&entry.Movie.Year,
+			&entry.Movie.Runtime,
+			pq.Array(&entry.Movie.Genres),
+			&entry.Movie.Version,
+			&entry.Movie.Rating,
+			&entry.Movie.RatingCount,
+			&entry.Movie.Poster,
+			pq.Array(&entry.Movie.PosterSizes),
+			&entry.Movie.ExternalIDs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// AddEntry appends a movie to the end of a list, returning ErrDuplicateListEntry if the list already holds it. The
+// Position and AddedAt fields of entry are populated.
+func (m ListModel) AddEntry(entry *ListEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Without the lock two adds could read the same max(position) and place their movies at the same position.
+	err = lockList(ctx, tx, entry.ListID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO list_entries (list_id, movie_id, position, notes)
+	SELECT $1, $2, COALESCE(max(position), 0) + 1, $3
+	FROM list_entries
+	WHERE list_id = $1
+	RETURNING position, added_at`
+
+	err = tx.QueryRowContext(ctx, query, entry.ListID, entry.Movie.ID, entry.Notes).Scan(&entry.Position, &entry.AddedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "list_entries_pkey"`:
+			return ErrDuplicateListEntry
+		case err.Error() == `pq: insert or update on table "list_entries" violates foreign key constraint "list_entries_movie_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetEntryNotes replaces the notes of a list entry.
+func (m ListModel) SetEntryNotes(listID, movieID int64, notes string) error {
+	query := `
+	UPDATE list_entries
+	SET notes = $3
+	WHERE list_id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, listID, movieID, notes)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// MoveEntry moves a movie to the given 1-based position on a list, shifting the entries between its old and new
+// positions. Positions beyond the end of the list move the movie to the end.
+func (m ListModel) MoveEntry(listID, movieID int64, position int32) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the list stops a concurrent move, add or remove from interleaving with the renumbering below. Locking
+	// the entries alone would not, as an entry added meanwhile is a new row which those locks do not cover.
+	err = lockList(ctx, tx, listID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT movie_id
+	FROM list_entries
+	WHERE list_id = $1
+	ORDER BY position, movie_id`, listID)
+	if err != nil {
+		return err
+	}
+
+	var order []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		order = append(order, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	order, ok := moveID(order, movieID, position)
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE list_entries
+	SET position = ordered.position
+	FROM unnest($2::bigint[]) WITH ORDINALITY AS ordered(movie_id, position)
+	WHERE list_entries.list_id = $1 AND list_entries.movie_id = ordered.movie_id`, listID, pq.Array(order))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveEntry removes a movie from a list, closing the gap it leaves.
+func (m ListModel) RemoveEntry(listID, movieID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockList(ctx, tx, listID)
+	if err != nil {
+		return err
+	}
+
+	var position int32
+	err = tx.QueryRowContext(ctx, `
+	DELETE FROM list_entries
+	WHERE list_id = $1 AND movie_id = $2
+	RETURNING position`, listID, movieID).Scan(&position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE list_entries
+	SET position = position - 1
+	WHERE list_id = $1 AND position > $2`, listID, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockList locks the row of a list until tx ends, so that changes to the positions of its entries are made one at a
+// time. It returns ErrRecordNotFound if there is no such list.
+func lockList(ctx context.Context, tx *sql.Tx, listID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM lists WHERE id = $1 FOR UPDATE`, listID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveID moves id to the 1-based position within ids, reporting false if ids does not hold it.
+func moveID(ids []int64, id int64, position int32) ([]int64, bool) {
+	from := -1
+	for i, candidate := range ids {
+		if candidate == id {
+			from = i
+			break
+		}
+	}
+	if from == -1 {
+		return ids, false
+	}
+
+	to := int(position) - 1
+	switch {
+	case to < 0:
+		to = 0
+	case to >= len(ids):
+		to = len(ids) - 1
+	}
+
+	moved := make([]int64, 0, len(ids))
+	moved = append(moved, ids[:from]...)
+	moved = append(moved, ids[from+1:]...)
+	moved = append(moved[:to], append([]int64{id}, moved[to:]...)...)
+	return moved, true
+}
+
+// scanList scans a lists row, preceded by any extra destinations (such as a window count).
+func scanList(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*List, error) {
+	var list List
+
+	dest := append(extra,
+		&list.ID,
+		&list.CreatedAt,
+		&list.UserID,
+		&list.Name,
+		&list.ShareHash,
+		&list.Version,
+	)
+
+	if err := row.Scan(dest...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	list.Shared = list.ShareHash != nil
+	return &list, nil
+}
+
+// ValidateList checks the fields of a list before it is saved.
+func ValidateList(v *validator.Validator, list *List) {
+	v.Check(list.Name != "", "name", "must be provided")
+	v.Check(len(list.Name) <= 100, "name", "must not be longer than 100 bytes")
+}
+
+// ValidateListEntry checks the fields of a list entry before it is saved.
+func ValidateListEntry(v *validator.Validator, entry *ListEntry) {
+	v.Check(entry.Movie != nil && entry.Movie.ID > 0, "movie_id", "must be provided")
+	v.Check(len(entry.Notes) <= 2_000, "notes", "must not be longer than 2000 bytes")
+}
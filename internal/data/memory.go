@@ -0,0 +1,245 @@
+package data
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// memoryPermissionCodes mirrors the rows seeded into the permissions table by the migrations. AddForUser silently
+// ignores unknown codes in the same way the INSERT ... SELECT in PermissionModel does.
+var memoryPermissionCodes = []string{"movies:read", "movies:write", "movies:admin", "movies:export", "lists:read", "lists:write"}
+
+// memoryDB holds the tables backing the in-memory models. A single mutex guards every table so that operations which
+// span tables (e.g. GetForToken joining users to tokens) observe a consistent view, as they would within PostgreSQL.
+type memoryDB struct {
+	mu sync.RWMutex
+
+	movies          map[int64]*Movie
+	revisions       map[int64][]*Revision // keyed by movie ID, in version order
+	people          map[int64]*Person
+	credits         map[int64]*Credit
+	ratings         map[int64]*Rating
+	lists           map[int64]*List
+	listEntries     map[int64][]*ListEntry // keyed by list ID, in position order
+	genres          map[int64]*Genre
+	titles          map[int64]*Title
+	users           map[int64]*User
+	tokens          map[string]*Token // keyed by string(Token.Hash)
+	permissions     map[string]bool
+	userPermissions map[int64]map[string]bool
+
+	// sequences emulates bigserial columns, keyed by table name.
+	sequences map[string]int64
+}
+
+// NewMemoryModels returns Models backed by process memory rather than PostgreSQL. It reproduces the semantics of the
+// SQL models (optimistic locking, unique emails, token expiry, genre containment) and is intended for development
+// and testing. Data is lost when the process exits.
+func NewMemoryModels() Models {
+	db := &memoryDB{
+		movies:          make(map[int64]*Movie),
+		revisions:       make(map[int64][]*Revision),
+		people:          make(map[int64]*Person),
+		credits:         make(map[int64]*Credit),
+		ratings:         make(map[int64]*Rating),
+		lists:           make(map[int64]*List),
+		listEntries:     make(map[int64][]*ListEntry),
+		genres:          make(map[int64]*Genre),
+		titles:          make(map[int64]*Title),
+		users:           make(map[int64]*User),
+		tokens:          make(map[string]*Token),
+		permissions:     make(map[string]bool),
+		userPermissions: make(map[int64]map[string]bool),
+		sequences:       make(map[string]int64),
+	}
+
+	for _, code := range memoryPermissionCodes {
+		db.permissions[code] = true
+	}
+
+	for _, genre := range memoryGenres {
+		c := copyGenre(genre)
+		c.ID, c.Version = db.nextID("genres"), 1
+		db.genres[c.ID] = c
+	}
+
+	return Models{
+		Movies:      memoryMovieModel{db: db},
+		Users:       memoryUserModel{db: db},
+		Tokens:      memoryTokenModel{db: db},
+		Permissions: memoryPermissionModel{db: db},
+		Revisions:   memoryRevisionModel{db: db},
+		People:      memoryPersonModel{db: db},
+		Credits:     memoryCreditModel{db: db},
+		Ratings:     memoryRatingModel{db: db},
+		Lists:       memoryListModel{db: db},
+		Genres:      memoryGenreModel{db: db},
+		Titles:      memoryTitleModel{db: db},
+	}
+}
+
+// nextID returns the next value of the named sequence. The caller must hold the write lock.
+func (db *memoryDB) nextID(table string) int64 {
+	db.sequences[table]++
+	return db.sequences[table]
+}
+
+// deleteMovie removes a movie along with the rows which reference it, as ON DELETE CASCADE does. The caller must hold
+// the write lock.
+func (db *memoryDB) deleteMovie(id int64) {
+	delete(db.movies, id)
+	delete(db.revisions, id)
+	for creditID, credit := range db.credits {
+		if credit.MovieID == id {
+			delete(db.credits, creditID)
+		}
+	}
+	for ratingID, rating := range db.ratings {
+		if rating.MovieID == id {
+			delete(db.ratings, ratingID)
+		}
+	}
+	for titleID, title := range db.titles {
+		if title.MovieID == id {
+			delete(db.titles, titleID)
+		}
+	}
+	for listID, entries := range db.listEntries {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.Movie.ID != id {
+				kept = append(kept, entry)
+			}
+		}
+		db.listEntries[listID] = kept
+	}
+}
+
+// deleteUser removes a user along with the rows which reference them, as ON DELETE CASCADE does, and clears them from
+// the revisions they made, as ON DELETE SET NULL does. The caller must hold the write lock.
+func (db *memoryDB) deleteUser(id int64) {
+	delete(db.users, id)
+	delete(db.userPermissions, id)
+	for key, token := range db.tokens {
+		if token.UserID == id {
+			delete(db.tokens, key)
+		}
+	}
+	for ratingID, rating := range db.ratings {
+		if rating.UserID == id {
+			delete(db.ratings, ratingID)
+			db.refreshRating(rating.MovieID)
+		}
+	}
+	for listID, list := range db.lists {
+		if list.UserID == id {
+			delete(db.lists, listID)
+			delete(db.listEntries, listID)
+		}
+	}
+	for _, revisions := range db.revisions {
+		for _, revision := range revisions {
+			if revision.UserID != nil && *revision.UserID == id {
+				revision.UserID = nil
+			}
+		}
+	}
+}
+
+// externalIDsTaken reports whether any of ids is held by a movie other than exceptID, including movies in the trash
+// as the unique indexes on movies do. The caller must hold the lock.
+func (db *memoryDB) externalIDsTaken(ids ExternalIDs, exceptID int64) bool {
+	for _, movie := range db.movies {
+		if movie.ID == exceptID {
+			continue
+		}
+		for source, id := range ids {
+			if movie.ExternalIDs[source] == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// now returns the current time truncated to match the timestamp(0) columns.
+func (db *memoryDB) now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// copyStrings returns a copy of s so callers cannot mutate slices held by the store.
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	c := make([]string, len(s))
+	copy(c, s)
+	return c
+}
+
+// simpleLexemes approximates to_tsvector('simple', s): the text is lower-cased and split into words on anything which
+// is not a letter or a digit.
+func simpleLexemes(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigramSimilarity approximates similarity(a, b) from pg_trgm: the number of trigrams shared by a and b divided by
+// the number of distinct trigrams in either. Trigrams are taken from each lower-cased word padded with two spaces
+// in front and one behind.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range ta {
+		if tb[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams returns the set of trigrams of s as pg_trgm extracts them.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range simpleLexemes(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// containsInt64 reports whether value is present in set.
+func containsInt64(set []int64, value int64) bool {
+	for _, candidate := range set {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAll reports whether every value in subset is present in set, matching the PostgreSQL @> array operator.
+func containsAll(set, subset []string) bool {
+	for _, value := range subset {
+		found := false
+		for _, candidate := range set {
+			if candidate == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"sort"
+)
+
+// memoryGenres mirrors the taxonomy seeded into the genres table by migration 000015.
+var memoryGenres = []*Genre{
+	{Slug: "action", Name: "Action"},
+	{Slug: "adventure", Name: "Adventure"},
+	{Slug: "animation", Name: "Animation", Aliases: []string{"animated", "cartoon"}},
+	{Slug: "biography", Name: "Biography", Aliases: []string{"biopic"}},
+	{Slug: "comedy", Name: "Comedy"},
+	{Slug: "crime", Name: "Crime"},
+	{Slug: "documentary", Name: "Documentary", Aliases: []string{"doc"}},
+	{Slug: "drama", Name: "Drama"},
+	{Slug: "family", Name: "Family"},
+	{Slug: "fantasy", Name: "Fantasy"},
+	{Slug: "history", Name: "History", Aliases: []string{"historical"}},
+	{Slug: "horror", Name: "Horror"},
+	{Slug: "music", Name: "Music"},
+	{Slug: "musical", Name: "Musical"},
+	{Slug: "mystery", Name: "Mystery"},
+	{Slug: "romance", Name: "Romance", Aliases: []string{"romantic"}},
+	{Slug: "science-fiction", Name: "Science Fiction", Aliases: []string{"sci-fi", "scifi", "sf"}},
+	{Slug: "sport", Name: "Sport", Aliases: []string{"sports"}},
+	{Slug: "thriller", Name: "Thriller", Aliases: []string{"suspense"}},
+	{Slug: "war", Name: "War"},
+	{Slug: "western", Name: "Western"},
+}
+
+// memoryGenreModel is the in-memory counterpart of GenreModel.
+type memoryGenreModel struct {
+	db *memoryDB
+}
+
+// copyGenre returns a copy of genre which shares no slice with it, with Aliases never nil as scanGenre ensures.
+func copyGenre(genre *Genre) *Genre {
+	c := *genre
+	c.Aliases = copyStrings(genre.Aliases)
+	if c.Aliases == nil {
+		c.Aliases = []string{}
+	}
+	return &c
+}
+
+// checkUnique returns ErrDuplicateGenre if another genre uses the slug or an alias of genre. The caller must hold the
+// lock.
+func (m memoryGenreModel) checkUnique(genre *Genre) error {
+	names := append([]string{genre.Slug}, genre.Aliases...)
+	for _, other := range m.db.genres {
+		if other.ID != genre.ID && (containsAny(names, []string{other.Slug}) || containsAny(names, other.Aliases)) {
+			return ErrDuplicateGenre
+		}
+	}
+	return nil
+}
+
+// Insert adds a genre to the taxonomy, returning ErrDuplicateGenre if its slug or an alias is already taken.
+func (m memoryGenreModel) Insert(genre *Genre) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if err := m.checkUnique(genre); err != nil {
+		return err
+	}
+
+	genre.ID = m.db.nextID("genres")
+	genre.Version = 1
+
+	m.db.genres[genre.ID] = copyGenre(genre)
+	return nil
+}
+
+// Get retrieves a single genre.
+func (m memoryGenreModel) Get(id int64) (*Genre, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	genre, ok := m.db.genres[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return copyGenre(genre), nil
+}
+
+// GetAll returns the whole taxonomy ordered by slug.
+func (m memoryGenreModel) GetAll() ([]*Genre, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	genres := make([]*Genre, 0, len(m.db.genres))
+	for _, genre := range m.db.genres {
+		genres = append(genres, copyGenre(genre))
+	}
+
+	sort.Slice(genres, func(i, j int) bool { return genres[i].Slug < genres[j].Slug })
+	return genres, nil
+}
+
+// Update saves a genre provided the version has not changed since it was read, otherwise ErrEditConflict. A change
+// of slug is carried through to the movies as GenreModel.Update does.
+func (m memoryGenreModel) Update(genre *Genre, userID *int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.genres[genre.ID]
+	if !ok || stored.Version != genre.Version {
+		return ErrEditConflict
+	}
+
+	oldSlug := stored.Slug
+	if oldSlug != genre.Slug && !containsAll(genre.Aliases, []string{oldSlug}) {
+		if len(genre.Aliases) >= MaxGenreAliases {
+			return ErrTooManyAliases
+		}
+		genre.Aliases = append(genre.Aliases, oldSlug)
+	}
+
+	if err := m.checkUnique(genre); err != nil {
+		return err
+	}
+
+	genre.Version++
+	m.db.genres[genre.ID] = copyGenre(genre)
+
+	if oldSlug != genre.Slug {
+		for _, movie := range m.db.movies {
+			if !containsAll(movie.Genres, []string{oldSlug}) {
+				continue
+			}
+			for i, slug := range movie.Genres {
+				if slug == oldSlug {
+					movie.Genres[i] = genre.Slug
+				}
+			}
+			movie.Version++
+			m.db.addRevision(movie, RevisionUpdate, userID)
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a genre from the taxonomy, returning ErrGenreInUse while any movie still carries it.
+func (m memoryGenreModel) Delete(id int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	genre, ok := m.db.genres[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	for _, movie := range m.db.movies {
+		if containsAll(movie.Genres, []string{genre.Slug}) {
+			return ErrGenreInUse
+		}
+	}
+
+	delete(m.db.genres, id)
+	return nil
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMemoryGenreRenameSavesMovies(t *testing.T) {
+	models := NewMemoryModels()
+	userID := int64(1)
+
+	renamed := newTestMovie("Moana", 2016)
+	renamed.Genres = []string{"animation", "drama"}
+	untouched := newTestMovie("Heat", 1995)
+	untouched.Genres = []string{"crime"}
+	for _, movie := range []*Movie{renamed, untouched} {
+		if err := models.Movies.Insert(movie, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	genres, err := models.Genres.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var genre *Genre
+	for _, g := range genres {
+		if g.Slug == "animation" {
+			genre = g
+		}
+	}
+	if genre == nil {
+		t.Fatal("animation is not in the taxonomy")
+	}
+
+	genre.Slug = "animated-film"
+	if err := models.Genres.Update(genre, &userID); err != nil {
+		t.Fatal(err)
+	}
+
+	movie, err := models.Movies.Get(renamed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Version != 2 || !equalStrings(movie.Genres, []string{"animated-film", "drama"}) {
+		t.Errorf("got genres %v at version %d; want [animated-film drama] at version 2", movie.Genres, movie.Version)
+	}
+
+	revision, err := models.Revisions.Get(renamed.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.Action != RevisionUpdate || revision.UserID == nil || *revision.UserID != userID {
+		t.Errorf("got revision %+v; want an update by user %d", revision, userID)
+	}
+
+	movie, err = models.Movies.Get(untouched.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Version != 1 {
+		t.Errorf("got version %d of a movie without the genre; want 1", movie.Version)
+	}
+}
+
+func TestMemoryGenreRenameAliasLimit(t *testing.T) {
+	models := NewMemoryModels()
+
+	genre := &Genre{Slug: "anime", Name: "Anime"}
+	for i := 0; i < MaxGenreAliases; i++ {
+		genre.Aliases = append(genre.Aliases, fmt.Sprintf("anime-%d", i))
+	}
+	if err := models.Genres.Insert(genre); err != nil {
+		t.Fatal(err)
+	}
+
+	// There is no room left to keep the old slug as an alias.
+	genre.Slug = "japanese-animation"
+	if err := models.Genres.Update(genre, nil); !errors.Is(err, ErrTooManyAliases) {
+		t.Fatalf("got error %v renaming a genre with %d aliases; want ErrTooManyAliases", err, MaxGenreAliases)
+	}
+
+	stored, err := models.Genres.Get(genre.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Slug != "anime" || len(stored.Aliases) != MaxGenreAliases {
+		t.Errorf("got slug %s with %d aliases; want the genre left as anime with %d", stored.Slug, len(stored.Aliases), MaxGenreAliases)
+	}
+
+	// Dropping an alias makes room for the old slug.
+	genre.Aliases = genre.Aliases[1:]
+	if err := models.Genres.Update(genre, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(genre.Aliases) != MaxGenreAliases || genre.Aliases[MaxGenreAliases-1] != "anime" {
+		t.Errorf("got aliases %v; want the old slug kept as the last", genre.Aliases)
+	}
+}
@@ -0,0 +1,252 @@
+package data
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sort"
+	"strings"
+)
+
+// memoryListModel is the in-memory counterpart of ListModel. Entries are held in position order, so an entry's
+// position is its index plus one.
+type memoryListModel struct {
+	db *memoryDB
+}
+
+// copyList returns a copy of list without its entries.
+func copyList(list *List) *List {
+	c := *list
+	c.ShareHash = append([]byte(nil), list.ShareHash...)
+	c.Shared = c.ShareHash != nil
+	c.Entries = nil
+	return &c
+}
+
+// nameTaken reports whether the user has a list other than id with the given name. The caller must hold the lock.
+func (m memoryListModel) nameTaken(userID, id int64, name string) bool {
+	for _, list := range m.db.lists {
+		if list.UserID == userID && list.ID != id && list.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Insert creates a new list, returning ErrDuplicateListName if the user already has a list of that name.
+func (m memoryListModel) Insert(list *List) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if m.nameTaken(list.UserID, 0, list.Name) {
+		return ErrDuplicateListName
+	}
+
+	list.ID = m.db.nextID("lists")
+	list.CreatedAt = m.db.now()
+	list.Version = 1
+
+	m.db.lists[list.ID] = copyList(list)
+	return nil
+}
+
+// Get retrieves a single list, without its entries.
+func (m memoryListModel) Get(id int64) (*List, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	list, ok := m.db.lists[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return copyList(list), nil
+}
+
+// GetForShareToken retrieves the list shared under the given plaintext token.
+func (m memoryListModel) GetForShareToken(plaintext string) (*List, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	for _, list := range m.db.lists {
+		if list.ShareHash != nil && bytes.Equal(list.ShareHash, hash[:]) {
+			return copyList(list), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+// GetAllForUser returns a page of the lists belonging to a user, without their entries.
+func (m memoryListModel) GetAllForUser(userID int64, filters Filters) ([]*List, Metadata, error) {
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	m.db.mu.RLock()
+	var matches []*List
+	for _, list := range m.db.lists {
+		if list.UserID == userID {
+			matches = append(matches, copyList(list))
+		}
+	}
+	m.db.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		return compareKeys(keys, func(column string) int {
+			if column == "name" {
+				return strings.Compare(a.Name, b.Name)
+			}
+			return compareInt64(a.ID, b.ID)
+		}) < 0
+	})
+
+	lists := []*List{}
+	for i := filters.offset(); i < len(matches) && len(lists) < filters.limit(); i++ {
+		lists = append(lists, matches[i])
+	}
+
+	totalRecords := len(matches)
+	if len(lists) == 0 {
+		totalRecords = 0
+	}
+
+	return lists, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update saves the name and sharing of a list provided the version has not changed since it was read, otherwise
+// ErrEditConflict.
+func (m memoryListModel) Update(list *List) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.lists[list.ID]
+	if !ok || stored.Version != list.Version {
+		return ErrEditConflict
+	}
+	if m.nameTaken(stored.UserID, list.ID, list.Name) {
+		return ErrDuplicateListName
+	}
+
+	list.Version++
+	updated := copyList(list)
+	updated.UserID, updated.CreatedAt = stored.UserID, stored.CreatedAt
+	m.db.lists[list.ID] = updated
+	return nil
+}
+
+// Delete removes a list along with its entries.
+func (m memoryListModel) Delete(id int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.lists[id]; !ok {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.lists, id)
+	delete(m.db.listEntries, id)
+	return nil
+}
+
+// GetEntries returns the entries of a list in order, leaving out movies in the trash.
+func (m memoryListModel) GetEntries(listID int64) ([]*ListEntry, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	entries := []*ListEntry{}
+	for i, entry := range m.db.listEntries[listID] {
+		movie, ok := m.db.movies[entry.Movie.ID]
+		if !ok || movie.DeletedAt != nil {
+			continue
+		}
+
+		c := *entry
+		c.Movie = copyMovie(movie)
+		c.Position = int32(i + 1)
+		entries = append(entries, &c)
+	}
+
+	return entries, nil
+}
+
+// AddEntry appends a movie to the end of a list, returning ErrDuplicateListEntry if the list already holds it.
+func (m memoryListModel) AddEntry(entry *ListEntry) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.lists[entry.ListID]; !ok {
+		return ErrRecordNotFound
+	}
+	if _, ok := m.db.movies[entry.Movie.ID]; !ok {
+		return ErrRecordNotFound
+	}
+
+	for _, existing := range m.db.listEntries[entry.ListID] {
+		if existing.Movie.ID == entry.Movie.ID {
+			return ErrDuplicateListEntry
+		}
+	}
+
+	entry.Position = int32(len(m.db.listEntries[entry.ListID]) + 1)
+	entry.AddedAt = m.db.now()
+
+	stored := *entry
+	stored.Movie = &Movie{ID: entry.Movie.ID}
+	m.db.listEntries[entry.ListID] = append(m.db.listEntries[entry.ListID], &stored)
+	return nil
+}
+
+// SetEntryNotes replaces the notes of a list entry.
+func (m memoryListModel) SetEntryNotes(listID, movieID int64, notes string) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	for _, entry := range m.db.listEntries[listID] {
+		if entry.Movie.ID == movieID {
+			entry.Notes = notes
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
+
+// MoveEntry moves a movie to the given 1-based position on a list.
+func (m memoryListModel) MoveEntry(listID, movieID int64, position int32) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	entries := m.db.listEntries[listID]
+	ids := make([]int64, len(entries))
+	byID := make(map[int64]*ListEntry, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.Movie.ID
+		byID[entry.Movie.ID] = entry
+	}
+
+	ids, ok := moveID(ids, movieID, position)
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	for i, id := range ids {
+		entries[i] = byID[id]
+	}
+	return nil
+}
+
+// RemoveEntry removes a movie from a list.
+func (m memoryListModel) RemoveEntry(listID, movieID int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	entries := m.db.listEntries[listID]
+	for i, entry := range entries {
+		if entry.Movie.ID == movieID {
+			m.db.listEntries[listID] = append(entries[:i], entries[i+1:]...)
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
@@ -0,0 +1,528 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memoryMovieModel is the in-memory counterpart of MovieModel.
+type memoryMovieModel struct {
+	db *memoryDB
+}
+
+// copyMovie returns a deep copy of movie so the caller and the store never share a Genres slice.
+func copyMovie(movie *Movie) *Movie {
+	c := *movie
+	c.Genres = copyStrings(movie.Genres)
+	c.PosterSizes = copyStrings(movie.PosterSizes)
+	c.ExternalIDs = copyExternalIDs(movie.ExternalIDs)
+	c.Posters = nil
+	if movie.DeletedAt != nil {
+		deletedAt := *movie.DeletedAt
+		c.DeletedAt = &deletedAt
+	}
+	return &c
+}
+
+// Insert adds a movie, populating the ID, CreatedAt and Version fields as the RETURNING clause would.
+func (m memoryMovieModel) Insert(movie *Movie, userID *int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if m.db.externalIDsTaken(movie.ExternalIDs, 0) {
+		return ErrDuplicateExternalID
+	}
+
+	movie.ID = m.db.nextID("movies")
+	movie.CreatedAt = m.db.now()
+	movie.Version = 1
+
+	m.db.movies[movie.ID] = copyMovie(movie)
+	m.db.addRevision(movie, RevisionInsert, userID)
+	return nil
+}
+
+// InsertBatch adds several movies at once. Holding the lock throughout makes the batch atomic, as the transaction in
+// MovieModel.InsertBatch does.
+func (m memoryMovieModel) InsertBatch(movies []*Movie, userID *int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	// Check the whole batch, against itself as well as the store, before saving any of it.
+	batch := make(map[string]bool)
+	for i, movie := range movies {
+		if m.db.externalIDsTaken(movie.ExternalIDs, 0) {
+			return &BatchError{Index: i, Err: ErrDuplicateExternalID}
+		}
+		for source, id := range movie.ExternalIDs {
+			if batch[source+":"+id] {
+				return &BatchError{Index: i, Err: ErrDuplicateExternalID}
+			}
+			batch[source+":"+id] = true
+		}
+	}
+
+	for _, movie := range movies {
+		movie.ID = m.db.nextID("movies")
+		movie.CreatedAt = m.db.now()
+		movie.Version = 1
+
+		m.db.movies[movie.ID] = copyMovie(movie)
+		m.db.addRevision(movie, RevisionInsert, userID)
+	}
+	return nil
+}
+
+// Get retrieves a single movie. Every field is copied whichever fields are asked for, as there are no columns to
+// save reading.
+func (m memoryMovieModel) Get(id int64, fields ...string) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	movie, ok := m.db.movies[id]
+	if !ok || movie.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyMovie(movie), nil
+}
+
+// Update replaces a movie provided the version has not changed since it was read, otherwise ErrEditConflict.
+func (m memoryMovieModel) Update(movie *Movie, action string, userID *int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.movies[movie.ID]
+	if !ok || stored.Version != movie.Version || stored.DeletedAt != nil {
+		return ErrEditConflict
+	}
+
+	if m.db.externalIDsTaken(movie.ExternalIDs, movie.ID) {
+		return ErrDuplicateExternalID
+	}
+
+	movie.Version++
+	updated := copyMovie(movie)
+	updated.CreatedAt = stored.CreatedAt
+	updated.DeletedAt = nil
+	updated.Rating, updated.RatingCount = stored.Rating, stored.RatingCount
+	updated.Poster, updated.PosterSizes = stored.Poster, copyStrings(stored.PosterSizes)
+	m.db.movies[movie.ID] = updated
+	m.db.addRevision(updated, action, userID)
+
+	return nil
+}
+
+// Delete moves a single movie to the trash provided the version is current, otherwise ErrEditConflict.
+func (m memoryMovieModel) Delete(movie *Movie, userID *int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.movies[movie.ID]
+	if !ok || stored.Version != movie.Version || stored.DeletedAt != nil {
+		return ErrEditConflict
+	}
+
+	deletedAt := m.db.now()
+	stored.DeletedAt = &deletedAt
+	stored.Version++
+	m.db.addRevision(stored, RevisionDelete, userID)
+
+	movie.Version = stored.Version
+	movie.DeletedAt = &deletedAt
+	return nil
+}
+
+// Restore takes a movie out of the trash.
+func (m memoryMovieModel) Restore(id int64, userID *int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	movie, ok := m.db.movies[id]
+	if !ok || movie.DeletedAt == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	movie.DeletedAt = nil
+	movie.Version++
+	m.db.addRevision(movie, RevisionRestore, userID)
+	return copyMovie(movie), nil
+}
+
+// PurgeDeleted permanently removes movies which were moved to the trash before the given time, returning the IDs of
+// those removed in ascending order.
+func (m memoryMovieModel) PurgeDeleted(before time.Time) ([]int64, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	var purged []int64
+	for id, movie := range m.db.movies {
+		if movie.DeletedAt != nil && movie.DeletedAt.Before(before) {
+			m.db.deleteMovie(id)
+			purged = append(purged, id)
+		}
+	}
+
+	sort.Slice(purged, func(i, j int) bool { return purged[i] < purged[j] })
+	return purged, nil
+}
+
+// FindDuplicates returns up to five live movies from the same year as movie with near-identical titles, most similar
+// first, using the trigram similarity of pg_trgm.
+func (m memoryMovieModel) FindDuplicates(movie *Movie) ([]*Movie, error) {
+	type candidate struct {
+		movie      *Movie
+		similarity float64
+	}
+
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	var candidates []candidate
+	for _, stored := range m.db.movies {
+		if stored.DeletedAt != nil || stored.Year != movie.Year {
+			continue
+		}
+		if similarity := trigramSimilarity(stored.Title, movie.Title); similarity >= DuplicateTitleSimilarity {
+			candidates = append(candidates, candidate{copyMovie(stored), similarity})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].similarity != candidates[j].similarity {
+			return candidates[i].similarity > candidates[j].similarity
+		}
+		return candidates[i].movie.ID < candidates[j].movie.ID
+	})
+
+	movies := []*Movie{}
+	for i := 0; i < len(candidates) && i < 5; i++ {
+		movies = append(movies, candidates[i].movie)
+	}
+	return movies, nil
+}
+
+// SetPoster replaces the poster of a live movie, returning the poster it replaced.
+func (m memoryMovieModel) SetPoster(id int64, poster string) (string, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	movie, ok := m.db.movies[id]
+	if !ok || movie.DeletedAt != nil {
+		return "", ErrRecordNotFound
+	}
+
+	previous := movie.Poster
+	movie.Poster = poster
+	movie.PosterSizes = []string{}
+	if poster != "" {
+		movie.PosterSizes = []string{PosterOriginal}
+	}
+
+	return previous, nil
+}
+
+// AddPosterSize records that a size of the movie's poster has been stored, or returns ErrEditConflict if the poster
+// has since been replaced or removed.
+func (m memoryMovieModel) AddPosterSize(id int64, poster, size string) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	movie, ok := m.db.movies[id]
+	if !ok || movie.Poster != poster || containsAll(movie.PosterSizes, []string{size}) {
+		return ErrEditConflict
+	}
+
+	movie.PosterSizes = append(movie.PosterSizes, size)
+	return nil
+}
+
+// GetAll retrieves all movies which match the criteria, sorted and paginated according to filters. As with Get,
+// every field is copied whichever fields are asked for.
+func (m memoryMovieModel) GetAll(criteria MovieCriteria, filters Filters, fields ...string) ([]*Movie, Metadata, error) {
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	after, err := filters.cursor(keys)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	less := movieLess(keys)
+
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	matches := m.match(criteria)
+
+	sort.Slice(matches, func(i, j int) bool {
+		return less(matches[i], matches[j])
+	})
+
+	totalRecords := 0
+	if filters.IncludeTotal {
+		totalRecords = len(matches)
+	}
+
+	// Select the rows in query order: past the cursor, walking backwards for a backward cursor, or from the offset.
+	var window []*Movie
+	switch {
+	case after == nil:
+		if offset := filters.offset(); offset < len(matches) {
+			window = matches[offset:]
+		}
+	case after.Backward:
+		pivot, _ := after.pivot(keys)
+		for i := len(matches) - 1; i >= 0; i-- {
+			if less(matches[i], pivot) {
+				window = append(window, matches[i])
+			}
+		}
+	default:
+		pivot, _ := after.pivot(keys)
+		for _, movie := range matches {
+			if less(pivot, movie) {
+				window = append(window, movie)
+			}
+		}
+	}
+
+	var movies []*Movie
+	for i := 0; i < len(window) && i <= filters.limit(); i++ {
+		movies = append(movies, window[i])
+	}
+
+	// As with the SQL query, the total is read from the returned rows so it is unknown when the page is empty.
+	if len(movies) == 0 {
+		totalRecords = 0
+	}
+
+	movies, metadata := paginate(movies, totalRecords, filters, keys, after)
+	return movies, metadata, nil
+}
+
+// match returns copies of the movies satisfying criteria, with Rank and Headline set for a title search. It is the
+// in-memory counterpart of MovieCriteria.apply. The caller must hold the lock.
+func (m memoryMovieModel) match(criteria MovieCriteria) []*Movie {
+	query := parseTextQuery(criteria.Title, criteria.SearchConfig)
+
+	var matches []*Movie
+	for _, movie := range m.db.movies {
+		if !criteria.matches(movie) {
+			continue
+		}
+		if criteria.Person != 0 && !m.db.credited(movie.ID, criteria.Person) {
+			continue
+		}
+
+		sharedPeople := 0
+		if criteria.Similar != nil {
+			sharedPeople = m.db.sharedPeople(movie.ID, criteria.Similar.People)
+			if !criteria.Similar.candidate(movie, sharedPeople) {
+				continue
+			}
+		}
+
+		movie = copyMovie(movie)
+		if criteria.Title != "" && !m.search(movie, query, criteria.SearchConfig) {
+			continue
+		}
+		if criteria.Similar != nil {
+			movie.Similarity = criteria.Similar.score(movie, sharedPeople)
+		}
+		matches = append(matches, movie)
+	}
+
+	return matches
+}
+
+// search reports whether the title or an alternate title of movie matches query, setting the Rank and Headline of
+// movie as MovieCriteria.apply does: the rank is that of the best matching title and the headline is of the default
+// title when it matches, otherwise of the best matching alternate title. The caller must hold the lock.
+func (m memoryMovieModel) search(movie *Movie, query textQuery, config string) bool {
+	var (
+		best     *Title
+		bestRank float32
+	)
+	for _, title := range m.db.titlesOf(movie.ID) {
+		document := searchLexemes(title.Title, config)
+		if !query.matches(document) {
+			continue
+		}
+		if rank := query.rank(document); best == nil || rank > bestRank {
+			best, bestRank = title, rank
+		}
+	}
+
+	document := searchLexemes(movie.Title, config)
+	switch {
+	case query.matches(document):
+		movie.Rank = query.rank(document)
+		if best != nil && bestRank > movie.Rank {
+			movie.Rank = bestRank
+		}
+		movie.Headline = query.headline(movie.Title, config)
+	case best != nil:
+		movie.Rank = bestRank
+		movie.Headline = query.headline(best.Title, config)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Export calls fn with every movie matching criteria in the order given by filters. The matches are copied before fn
+// is called, so fn may take as long as it likes without holding up writers.
+func (m memoryMovieModel) Export(ctx context.Context, criteria MovieCriteria, filters Filters, fn func(*Movie) error) error {
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return err
+	}
+	less := movieLess(keys)
+
+	m.db.mu.RLock()
+	matches := m.match(criteria)
+	m.db.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return less(matches[i], matches[j])
+	})
+
+	for _, movie := range matches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(movie); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// movieLess orders movies by the sort keys, matching the ORDER BY of MovieModel.
+func movieLess(keys []sortKey) func(a, b *Movie) bool {
+	return func(a, b *Movie) bool {
+		return compareKeys(keys, func(column string) int { return compareMovies(a, b, column) }) < 0
+	}
+}
+
+// Facets counts the movies matching criteria by each of the named facets, ordered as MovieModel orders them.
+func (m memoryMovieModel) Facets(criteria MovieCriteria, names []string) (Facets, error) {
+	m.db.mu.RLock()
+	matches := m.match(criteria)
+	m.db.mu.RUnlock()
+
+	facets := make(Facets, len(names))
+
+	for _, name := range names {
+		counts := make(map[string]int)
+		decades := make(map[string]int32)
+
+		for _, movie := range matches {
+			switch name {
+			case "genres":
+				for _, genre := range movie.Genres {
+					counts[genre]++
+				}
+			case "decade":
+				decade := fmt.Sprintf("%ds", movie.Year/10*10)
+				counts[decade]++
+				decades[decade] = movie.Year / 10
+			case "runtime":
+				bucket := runtimeBucket(movie.Runtime)
+				counts[bucket]++
+			default:
+				return nil, fmt.Errorf("unknown facet %q", name)
+			}
+		}
+
+		facet := []FacetCount{}
+		for value, count := range counts {
+			facet = append(facet, FacetCount{Value: value, Count: count})
+		}
+
+		sort.Slice(facet, func(i, j int) bool {
+			a, b := facet[i], facet[j]
+			switch name {
+			case "genres":
+				if a.Count != b.Count {
+					return a.Count > b.Count
+				}
+				return a.Value < b.Value
+			case "runtime":
+				return bucketIndex(a.Value) < bucketIndex(b.Value)
+			default:
+				return decades[a.Value] < decades[b.Value]
+			}
+		})
+
+		facets[name] = facet
+	}
+
+	return facets, nil
+}
+
+// bucketIndex returns the position of the labelled bucket within runtimeBuckets.
+func bucketIndex(label string) int {
+	for i, bucket := range runtimeBuckets {
+		if bucket.label == label {
+			return i
+		}
+	}
+	return len(runtimeBuckets)
+}
+
+// compareMovies compares a and b by the named sort column, returning -1, 0 or +1.
+func compareMovies(a, b *Movie, column string) int {
+	switch column {
+	case "title":
+		return strings.Compare(a.Title, b.Title)
+	case "year":
+		return compareInt64(int64(a.Year), int64(b.Year))
+	case "runtime":
+		return compareInt64(int64(a.Runtime), int64(b.Runtime))
+	case "relevance":
+		return compareFloat32(a.Rank, b.Rank)
+	case "similarity":
+		return compareFloat32(a.Similarity, b.Similarity)
+	case "rating":
+		return compareFloat32(a.Rating, b.Rating)
+	default:
+		return compareInt64(a.ID, b.ID)
+	}
+}
+
+func compareFloat32(a, b float32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
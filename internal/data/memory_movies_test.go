@@ -0,0 +1,172 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+// newTestMovie returns a valid movie which has not been saved.
+func newTestMovie(title string, year int32) *Movie {
+	return &Movie{Title: title, Year: year, Runtime: 100, Genres: []string{"drama"}}
+}
+
+func TestMemoryMovieLifecycle(t *testing.T) {
+	models := NewMemoryModels()
+	userID := int64(1)
+
+	movie := newTestMovie("Moana", 2016)
+	if err := models.Movies.Insert(movie, &userID); err != nil {
+		t.Fatal(err)
+	}
+	if movie.ID == 0 || movie.Version != 1 {
+		t.Fatalf("got id %d at version %d; want an id at version 1", movie.ID, movie.Version)
+	}
+
+	// A second copy read before the update is now stale.
+	stale, err := models.Movies.Get(movie.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	movie.Year = 2017
+	if err := models.Movies.Update(movie, RevisionUpdate, &userID); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Movies.Update(stale, RevisionUpdate, &userID); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("got error %v updating a stale movie; want ErrEditConflict", err)
+	}
+
+	if err := models.Movies.Delete(movie, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Movies.Get(movie.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("got error %v reading a movie in the trash; want ErrRecordNotFound", err)
+	}
+
+	restored, err := models.Movies.Restore(movie.ID, &userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != 4 || restored.Year != 2017 {
+		t.Errorf("got year %d at version %d; want 2017 at version 4", restored.Year, restored.Version)
+	}
+
+	// Each change recorded its revision.
+	revisions, _, err := models.Revisions.GetAllForMovie(movie.ID, Filters{Page: 1, PageSize: 10, Sort: "version", SortSafeList: []string{"version"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := []string{RevisionInsert, RevisionUpdate, RevisionDelete, RevisionRestore}
+	if len(revisions) != len(actions) {
+		t.Fatalf("got %d revisions; want %d", len(revisions), len(actions))
+	}
+	for i, revision := range revisions {
+		if revision.Version != int32(i+1) || revision.Action != actions[i] {
+			t.Errorf("got revision %d %s; want %d %s", revision.Version, revision.Action, i+1, actions[i])
+		}
+		if (revision.UserID == nil) != (revision.Action == RevisionDelete) {
+			t.Errorf("revision %d attributed to %v", revision.Version, revision.UserID)
+		}
+	}
+	if revisions[1].Snapshot.Year != 2017 {
+		t.Errorf("got year %d in the update's snapshot; want 2017", revisions[1].Snapshot.Year)
+	}
+}
+
+func TestMemoryMovieInsertBatch(t *testing.T) {
+	models := NewMemoryModels()
+
+	existing := newTestMovie("Moana", 2016)
+	existing.ExternalIDs = ExternalIDs{"imdb": "tt3521164"}
+	if err := models.Movies.Insert(existing, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate := newTestMovie("Vaiana", 2016)
+	duplicate.ExternalIDs = ExternalIDs{"imdb": "tt3521164"}
+	batch := []*Movie{newTestMovie("Up", 2009), duplicate, newTestMovie("Coco", 2017)}
+
+	err := models.Movies.InsertBatch(batch, nil)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, ErrDuplicateExternalID) {
+		t.Fatalf("got error %v; want a duplicate external ID at index 1", err)
+	}
+
+	// Nothing in the rejected batch was saved.
+	movies, _, err := models.Movies.GetAll(MovieCriteria{}, Filters{Page: 1, PageSize: 10, Sort: "id", SortSafeList: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != 1 {
+		t.Fatalf("got %d movies after a rejected batch; want 1", len(movies))
+	}
+
+	if err := models.Movies.InsertBatch(append(batch[:1], batch[2:]...), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryMovieGetAllCursor(t *testing.T) {
+	models := NewMemoryModels()
+	for _, movie := range []*Movie{
+		newTestMovie("Up", 2009),
+		newTestMovie("Coco", 2017),
+		newTestMovie("Moana", 2016),
+		newTestMovie("Soul", 2020),
+		newTestMovie("Onward", 2020),
+	} {
+		if err := models.Movies.Insert(movie, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filters := Filters{Page: 1, PageSize: 2, Sort: "-year,title", SortSafeList: []string{"id", "title", "year"}}
+	want := [][]string{{"Onward", "Soul"}, {"Coco", "Moana"}, {"Up"}}
+
+	var pages []Metadata
+	for i := range want {
+		movies, metadata, err := models.Movies.GetAll(MovieCriteria{}, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(movies); !equalStrings(got, want[i]) {
+			t.Fatalf("page %d: got %v; want %v", i+1, got, want[i])
+		}
+		pages = append(pages, metadata)
+		filters.Cursor = metadata.NextCursor
+	}
+	if pages[2].NextCursor != "" {
+		t.Error("last page has a next cursor")
+	}
+
+	// The previous cursor of the last page walks back to the second.
+	filters.Cursor = pages[2].PrevCursor
+	movies, _, err := models.Movies.GetAll(MovieCriteria{}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(movies); !equalStrings(got, want[1]) {
+		t.Errorf("previous page: got %v; want %v", got, want[1])
+	}
+}
+
+func titles(movies []*Movie) []string {
+	var titles []string
+	for _, movie := range movies {
+		titles = append(titles, movie.Title)
+	}
+	return titles
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
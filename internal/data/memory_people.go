@@ -0,0 +1,242 @@
+package data
+
+import (
+	"sort"
+	"strings"
+)
+
+// memoryPersonModel is the in-memory counterpart of PersonModel.
+type memoryPersonModel struct {
+	db *memoryDB
+}
+
+// memoryCreditModel is the in-memory counterpart of CreditModel.
+type memoryCreditModel struct {
+	db *memoryDB
+}
+
+// creditRoleOrder ranks roles as the ORDER BY of CreditModel.GetAllForMovie does.
+var creditRoleOrder = map[string]int{"director": 0, "writer": 1, "cast": 2}
+
+// Insert adds a person, populating the ID, CreatedAt and Version fields.
+func (m memoryPersonModel) Insert(person *Person) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	person.ID = m.db.nextID("people")
+	person.CreatedAt = m.db.now()
+	person.Version = 1
+
+	c := *person
+	m.db.people[person.ID] = &c
+	return nil
+}
+
+// Get retrieves a single person.
+func (m memoryPersonModel) Get(id int64) (*Person, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	person, ok := m.db.people[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	c := *person
+	return &c, nil
+}
+
+// Update replaces a person provided the version has not changed since it was read, otherwise ErrEditConflict.
+func (m memoryPersonModel) Update(person *Person) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.people[person.ID]
+	if !ok || stored.Version != person.Version {
+		return ErrEditConflict
+	}
+
+	person.Version++
+	c := *person
+	c.CreatedAt = stored.CreatedAt
+	m.db.people[person.ID] = &c
+
+	return nil
+}
+
+// Delete removes a person along with their credits.
+func (m memoryPersonModel) Delete(id int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.people[id]; !ok {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.people, id)
+	for creditID, credit := range m.db.credits {
+		if credit.PersonID == id {
+			delete(m.db.credits, creditID)
+		}
+	}
+
+	return nil
+}
+
+// GetAll lists people whose name contains every word of name, or everyone when name is empty.
+func (m memoryPersonModel) GetAll(name string, filters Filters) ([]*Person, Metadata, error) {
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	words := simpleLexemes(name)
+
+	m.db.mu.RLock()
+	var matches []*Person
+	for _, person := range m.db.people {
+		if containsAll(simpleLexemes(person.Name), words) {
+			c := *person
+			matches = append(matches, &c)
+		}
+	}
+	m.db.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		return compareKeys(keys, func(column string) int {
+			if column == "name" {
+				return strings.Compare(a.Name, b.Name)
+			}
+			return compareInt64(a.ID, b.ID)
+		}) < 0
+	})
+
+	people := []*Person{}
+	for i := filters.offset(); i < len(matches) && len(people) < filters.limit(); i++ {
+		people = append(people, matches[i])
+	}
+
+	totalRecords := len(matches)
+	if len(people) == 0 {
+		totalRecords = 0
+	}
+
+	return people, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Insert credits a person on a movie, see CreditModel.Insert.
+func (m memoryCreditModel) Insert(credit *Credit) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	person, ok := m.db.people[credit.PersonID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	if _, ok := m.db.movies[credit.MovieID]; !ok {
+		return ErrRecordNotFound
+	}
+
+	for _, existing := range m.db.credits {
+		if existing.MovieID == credit.MovieID && existing.PersonID == credit.PersonID &&
+			existing.Role == credit.Role && existing.Character == credit.Character {
+			return ErrDuplicateCredit
+		}
+	}
+
+	credit.ID = m.db.nextID("movie_credits")
+	credit.Name = person.Name
+
+	c := *credit
+	m.db.credits[credit.ID] = &c
+	return nil
+}
+
+// Delete removes a single credit from a movie.
+func (m memoryCreditModel) Delete(movieID, id int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	credit, ok := m.db.credits[id]
+	if !ok || credit.MovieID != movieID {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.credits, id)
+	return nil
+}
+
+// GetAllForMovie lists the credits of a movie: directors, then writers, then the cast in billing order.
+func (m memoryCreditModel) GetAllForMovie(movieID int64) ([]*Credit, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	credits := m.db.creditsOf(movieID)
+	if credits == nil {
+		credits = []*Credit{}
+	}
+	return credits, nil
+}
+
+// GetAllForMovies lists the credits of several movies at once, keyed by movie ID.
+func (m memoryCreditModel) GetAllForMovies(movieIDs []int64) (map[int64][]*Credit, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	credits := make(map[int64][]*Credit)
+	for _, id := range movieIDs {
+		if movieCredits := m.db.creditsOf(id); movieCredits != nil {
+			credits[id] = movieCredits
+		}
+	}
+	return credits, nil
+}
+
+// creditsOf returns copies of the credits of a movie in the order GetAllForMovie lists them, or nil if it has none.
+// The caller must hold the lock.
+func (db *memoryDB) creditsOf(movieID int64) []*Credit {
+	var credits []*Credit
+	for _, credit := range db.credits {
+		if credit.MovieID != movieID {
+			continue
+		}
+		c := *credit
+		// The name is joined from people when read, so it follows any change to the person.
+		c.Name = db.people[credit.PersonID].Name
+		credits = append(credits, &c)
+	}
+
+	sort.Slice(credits, func(i, j int) bool {
+		a, b := credits[i], credits[j]
+		switch {
+		case a.Role != b.Role:
+			return creditRoleOrder[a.Role] < creditRoleOrder[b.Role]
+		case a.Billing != b.Billing:
+			return a.Billing < b.Billing
+		}
+		return a.ID < b.ID
+	})
+
+	return credits
+}
+
+// sharedPeople counts how many of people hold a credit on the movie. The caller must hold the lock.
+func (db *memoryDB) sharedPeople(movieID int64, people []int64) int {
+	shared := make(map[int64]bool)
+	for _, credit := range db.credits {
+		if credit.MovieID == movieID && containsInt64(people, credit.PersonID) {
+			shared[credit.PersonID] = true
+		}
+	}
+	return len(shared)
+}
+
+// credited reports whether the person holds any credit on the movie. The caller must hold the lock.
+func (db *memoryDB) credited(movieID, personID int64) bool {
+	for _, credit := range db.credits {
+		if credit.MovieID == movieID && credit.PersonID == personID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"sort"
+)
+
+// memoryPermissionModel is the in-memory counterpart of PermissionModel.
+type memoryPermissionModel struct {
+	db *memoryDB
+}
+
+// GetAllForUser returns the permission codes granted to the user.
+func (m memoryPermissionModel) GetAllForUser(userID int64) (Permissions, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	var permissions Permissions
+	for code := range m.db.userPermissions[userID] {
+		permissions = append(permissions, code)
+	}
+	sort.Strings(permissions)
+
+	return permissions, nil
+}
+
+// AddForUser grants the given codes to the user. Codes which do not exist are ignored.
+func (m memoryPermissionModel) AddForUser(userID int64, codes ...string) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[userID]; !ok {
+		return ErrRecordNotFound
+	}
+
+	if m.db.userPermissions[userID] == nil {
+		m.db.userPermissions[userID] = make(map[string]bool)
+	}
+
+	for _, code := range codes {
+		if m.db.permissions[code] {
+			m.db.userPermissions[userID][code] = true
+		}
+	}
+
+	return nil
+}
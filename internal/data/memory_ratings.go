@@ -0,0 +1,173 @@
+package data
+
+import (
+	"math"
+	"sort"
+)
+
+// memoryRatingModel is the in-memory counterpart of RatingModel.
+type memoryRatingModel struct {
+	db *memoryDB
+}
+
+// Insert adds a rating, returning ErrDuplicateRating if the user has already rated the movie.
+func (m memoryRatingModel) Insert(rating *Rating) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.movies[rating.MovieID]; !ok {
+		return ErrRecordNotFound
+	}
+
+	for _, existing := range m.db.ratings {
+		if existing.MovieID == rating.MovieID && existing.UserID == rating.UserID {
+			return ErrDuplicateRating
+		}
+	}
+
+	rating.ID = m.db.nextID("ratings")
+	rating.CreatedAt = m.db.now()
+	rating.Version = 1
+
+	c := *rating
+	m.db.ratings[rating.ID] = &c
+	m.db.refreshRating(rating.MovieID)
+	return nil
+}
+
+// GetForUser retrieves the rating a user gave a movie.
+func (m memoryRatingModel) GetForUser(movieID, userID int64) (*Rating, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	for _, rating := range m.db.ratings {
+		if rating.MovieID == movieID && rating.UserID == userID {
+			c := *rating
+			return &c, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+// Update saves a rating provided the version has not changed since it was read, otherwise ErrEditConflict.
+func (m memoryRatingModel) Update(rating *Rating) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.ratings[rating.ID]
+	if !ok || stored.Version != rating.Version {
+		return ErrEditConflict
+	}
+
+	rating.Version++
+	stored.Score, stored.Review, stored.Version = rating.Score, rating.Review, rating.Version
+	m.db.refreshRating(stored.MovieID)
+	return nil
+}
+
+// Delete removes a rating provided the version has not changed since it was read, otherwise ErrEditConflict.
+func (m memoryRatingModel) Delete(rating *Rating) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.ratings[rating.ID]
+	if !ok || stored.Version != rating.Version {
+		return ErrEditConflict
+	}
+
+	delete(m.db.ratings, rating.ID)
+	m.db.refreshRating(stored.MovieID)
+	return nil
+}
+
+// GetAllForMovie returns a page of the ratings of a movie.
+func (m memoryRatingModel) GetAllForMovie(movieID int64, filters Filters) ([]*Rating, Metadata, error) {
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	m.db.mu.RLock()
+	var matches []*Rating
+	for _, rating := range m.db.ratings {
+		if rating.MovieID == movieID {
+			c := *rating
+			matches = append(matches, &c)
+		}
+	}
+	m.db.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		return compareKeys(keys, func(column string) int {
+			if column == "score" {
+				return compareInt64(int64(a.Score), int64(b.Score))
+			}
+			return compareInt64(a.ID, b.ID)
+		}) < 0
+	})
+
+	ratings := []*Rating{}
+	for i := filters.offset(); i < len(matches) && len(ratings) < filters.limit(); i++ {
+		ratings = append(ratings, matches[i])
+	}
+
+	totalRecords := len(matches)
+	if len(ratings) == 0 {
+		totalRecords = 0
+	}
+
+	return ratings, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// refreshRating recalculates the aggregate rating of a movie, rounding the average to two decimal places as
+// adjustRating does. The caller must hold the write lock.
+func (db *memoryDB) refreshRating(movieID int64) {
+	movie, ok := db.movies[movieID]
+	if !ok {
+		return
+	}
+
+	var count, total int32
+	for _, rating := range db.ratings {
+		if rating.MovieID == movieID {
+			count++
+			total += rating.Score
+		}
+	}
+
+	movie.RatingCount = count
+	movie.Rating = 0
+	if count > 0 {
+		movie.Rating = float32(math.Round(float64(total)/float64(count)*100) / 100)
+	}
+}
+
+// GetLatestForMovies returns up to limit of the most recent ratings of each of several movies, keyed by movie ID.
+func (m memoryRatingModel) GetLatestForMovies(movieIDs []int64, limit int) (map[int64][]*Rating, error) {
+	m.db.mu.RLock()
+	ratings := make(map[int64][]*Rating)
+	for _, rating := range m.db.ratings {
+		if containsInt64(movieIDs, rating.MovieID) {
+			c := *rating
+			ratings[rating.MovieID] = append(ratings[rating.MovieID], &c)
+		}
+	}
+	m.db.mu.RUnlock()
+
+	for id, movieRatings := range ratings {
+		sort.Slice(movieRatings, func(i, j int) bool {
+			a, b := movieRatings[i], movieRatings[j]
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID > b.ID
+		})
+		if len(movieRatings) > limit {
+			ratings[id] = movieRatings[:limit]
+		}
+	}
+
+	return ratings, nil
+}
@@ -0,0 +1,91 @@
+package data
+
+import (
+	"sort"
+)
+
+// memoryRevisionModel is the in-memory counterpart of RevisionModel.
+type memoryRevisionModel struct {
+	db *memoryDB
+}
+
+// copyRevision returns a deep copy of revision.
+func copyRevision(revision *Revision) *Revision {
+	c := *revision
+	c.Snapshot.Genres = copyStrings(revision.Snapshot.Genres)
+	if revision.UserID != nil {
+		userID := *revision.UserID
+		c.UserID = &userID
+	}
+	return &c
+}
+
+// addRevision records the version movie has reached as a result of action, as MovieModel does within the transaction
+// making the change. The caller must hold the write lock.
+func (db *memoryDB) addRevision(movie *Movie, action string, userID *int64) {
+	revision := &Revision{
+		ID:        db.nextID("movie_revisions"),
+		MovieID:   movie.ID,
+		Version:   movie.Version,
+		Action:    action,
+		Snapshot:  SnapshotOf(movie),
+		CreatedAt: db.now(),
+	}
+	if userID != nil {
+		id := *userID
+		revision.UserID = &id
+	}
+
+	db.revisions[movie.ID] = append(db.revisions[movie.ID], revision)
+}
+
+// Get returns the revision recording the given version of a movie.
+func (m memoryRevisionModel) Get(movieID int64, version int32) (*Revision, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	for _, revision := range m.db.revisions[movieID] {
+		if revision.Version == version {
+			return copyRevision(revision), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+// GetAllForMovie returns a page of the revisions of a movie, sorted by version.
+func (m memoryRevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*Revision, Metadata, error) {
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	all := m.db.revisions[movieID]
+	sorted := make([]*Revision, len(all))
+	copy(sorted, all)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		return compareKeys(keys, func(column string) int {
+			if column == "version" {
+				return compareInt64(int64(a.Version), int64(b.Version))
+			}
+			return compareInt64(a.ID, b.ID)
+		}) < 0
+	})
+
+	revisions := []*Revision{}
+	for i := filters.offset(); i < len(sorted) && len(revisions) < filters.limit(); i++ {
+		revisions = append(revisions, copyRevision(sorted[i]))
+	}
+
+	totalRecords := len(sorted)
+	if len(revisions) == 0 {
+		totalRecords = 0
+	}
+
+	return revisions, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
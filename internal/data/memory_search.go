@@ -0,0 +1,206 @@
+package data
+
+import (
+	"strings"
+	"unicode"
+)
+
+// textQuery is the in-memory form of websearch_to_tsquery: a disjunction of groups, each group being a conjunction
+// of terms. A term of several words is a phrase, which must appear as consecutive words.
+type textQuery [][]textTerm
+
+type textTerm struct {
+	words  []string
+	negate bool
+}
+
+// parseTextQuery parses websearch syntax: unquoted words are ANDed, "quoted text" is a phrase, a leading - negates a
+// word or phrase and the word "or" separates alternatives.
+func parseTextQuery(query, config string) textQuery {
+	var (
+		groups textQuery
+		group  []textTerm
+	)
+
+	for _, token := range splitQuery(query) {
+		if strings.EqualFold(token, "or") {
+			if len(group) > 0 {
+				groups = append(groups, group)
+			}
+			group = nil
+			continue
+		}
+
+		term := textTerm{}
+		if strings.HasPrefix(token, "-") {
+			term.negate = true
+			token = token[1:]
+		}
+
+		term.words = searchLexemes(strings.Trim(token, `"`), config)
+		if len(term.words) > 0 {
+			group = append(group, term)
+		}
+	}
+
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// splitQuery splits a query on whitespace, keeping quoted phrases (optionally preceded by -) as a single token.
+func splitQuery(query string) []string {
+	var (
+		tokens  []string
+		current strings.Builder
+		quoted  bool
+	)
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// matches reports whether the document lexemes satisfy the query. A query without any lexemes matches nothing, as
+// with an empty tsquery.
+func (q textQuery) matches(document []string) bool {
+	for _, group := range q {
+		ok := len(group) > 0
+		for _, term := range group {
+			if term.in(document) == term.negate {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rank approximates ts_rank as the share of the document's words which the query's positive terms matched.
+func (q textQuery) rank(document []string) float32 {
+	if len(document) == 0 {
+		return 0
+	}
+
+	hits := 0
+	for _, word := range document {
+		if q.positive(word) {
+			hits++
+		}
+	}
+
+	return float32(hits) / float32(len(document))
+}
+
+// positive reports whether lexeme is one of the words the query is searching for, rather than excluding.
+func (q textQuery) positive(lexeme string) bool {
+	for _, group := range q {
+		for _, term := range group {
+			if term.negate {
+				continue
+			}
+			for _, word := range term.words {
+				if word == lexeme {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// in reports whether the term's words appear consecutively within document.
+func (t textTerm) in(document []string) bool {
+	for i := 0; i+len(t.words) <= len(document); i++ {
+		found := true
+		for j, word := range t.words {
+			if document[i+j] != word {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// headline approximates ts_headline by wrapping each word of text matched by the query in <b></b>.
+func (q textQuery) headline(text, config string) string {
+	var (
+		out  strings.Builder
+		word strings.Builder
+	)
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		lexemes := searchLexemes(word.String(), config)
+		if len(lexemes) == 1 && q.positive(lexemes[0]) {
+			out.WriteString("<b>" + word.String() + "</b>")
+		} else {
+			out.WriteString(word.String())
+		}
+		word.Reset()
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		out.WriteRune(r)
+	}
+	flush()
+
+	return out.String()
+}
+
+// searchLexemes approximates to_tsvector(config, s). The simple configuration only lower-cases words. Other
+// configurations also strip common suffixes so that "heroes" matches "hero", a crude stand-in for the Snowball
+// stemmers used by PostgreSQL.
+func searchLexemes(s, config string) []string {
+	words := simpleLexemes(s)
+	if config == "simple" {
+		return words
+	}
+
+	for i, word := range words {
+		words[i] = stem(word)
+	}
+	return words
+}
+
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "es", "ed", "s"} {
+		if len(word) > len(suffix)+2 && strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"sort"
+)
+
+// memoryTitleModel is the in-memory counterpart of TitleModel.
+type memoryTitleModel struct {
+	db *memoryDB
+}
+
+// Insert adds an alternate title to a movie, populating its ID.
+func (m memoryTitleModel) Insert(title *Title) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.movies[title.MovieID]; !ok {
+		return ErrRecordNotFound
+	}
+
+	for _, other := range m.db.titles {
+		if other.MovieID == title.MovieID && other.Language == title.Language && other.Region == title.Region {
+			return ErrDuplicateTitle
+		}
+	}
+
+	title.ID = m.db.nextID("movie_titles")
+
+	c := *title
+	m.db.titles[title.ID] = &c
+	return nil
+}
+
+// Delete removes a single alternate title from a movie.
+func (m memoryTitleModel) Delete(movieID, id int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	title, ok := m.db.titles[id]
+	if !ok || title.MovieID != movieID {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.titles, id)
+	return nil
+}
+
+// GetAllForMovie lists the alternate titles of a movie ordered by language and region.
+func (m memoryTitleModel) GetAllForMovie(movieID int64) ([]*Title, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	titles := m.db.titlesOf(movieID)
+	if titles == nil {
+		return []*Title{}, nil
+	}
+	return titles, nil
+}
+
+// GetAllForMovies returns the alternate titles of several movies at once keyed by movie ID.
+func (m memoryTitleModel) GetAllForMovies(movieIDs []int64) (map[int64][]*Title, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	titles := make(map[int64][]*Title)
+	for _, id := range movieIDs {
+		if movieTitles := m.db.titlesOf(id); movieTitles != nil {
+			titles[id] = movieTitles
+		}
+	}
+	return titles, nil
+}
+
+// titlesOf returns copies of the alternate titles of a movie ordered by language, region and ID, or nil if it has
+// none. The caller must hold the lock.
+func (db *memoryDB) titlesOf(movieID int64) []*Title {
+	var titles []*Title
+	for _, title := range db.titles {
+		if title.MovieID == movieID {
+			c := *title
+			titles = append(titles, &c)
+		}
+	}
+
+	sort.Slice(titles, func(i, j int) bool {
+		a, b := titles[i], titles[j]
+		if a.Language != b.Language {
+			return a.Language < b.Language
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.ID < b.ID
+	})
+
+	return titles
+}
@@ -0,0 +1,150 @@
+package data
+
+import (
+	"crypto/sha256"
+	"sort"
+	"time"
+)
+
+// memoryTokenModel is the in-memory counterpart of TokenModel.
+type memoryTokenModel struct {
+	db *memoryDB
+}
+
+// New generates a new token and stores it.
+func (m memoryTokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(token)
+	return token, err
+}
+
+// Insert stores a token keyed by its hash, populating the ID, CreatedAt and LastUsedAt fields. Only the hash is kept,
+// never the plaintext.
+func (m memoryTokenModel) Insert(token *Token) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[token.UserID]; !ok {
+		return ErrRecordNotFound
+	}
+
+	token.ID = m.db.nextID("tokens")
+	token.CreatedAt = m.db.now()
+	token.LastUsedAt = token.CreatedAt
+
+	stored := *token
+	stored.Plaintext = ""
+	stored.Expiry = stored.Expiry.Truncate(time.Second)
+	m.db.tokens[string(token.Hash)] = &stored
+
+	return nil
+}
+
+// DeleteAllForUser removes every token with the given scope belonging to the user.
+func (m memoryTokenModel) DeleteAllForUser(scope string, userID int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	for key, token := range m.db.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(m.db.tokens, key)
+		}
+	}
+
+	return nil
+}
+
+// NewSession generates a new authentication token for a client and stores it, see TokenModel.NewSession.
+func (m memoryTokenModel) NewSession(userID int64, ttl time.Duration, ip, userAgent string) (*Token, error) {
+	token, err := generateSession(userID, ttl, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(token)
+	return token, err
+}
+
+// Touch records that an authentication token has just been used, at most once per SessionTouchInterval.
+func (m memoryTokenModel) Touch(tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	token, ok := m.db.tokens[string(tokenHash[:])]
+	if ok && token.Scope == ScopeAuthentication && token.LastUsedAt.Before(time.Now().Add(-SessionTouchInterval)) {
+		token.LastUsedAt = m.db.now()
+	}
+
+	return nil
+}
+
+// Delete removes a single token given its plaintext.
+func (m memoryTokenModel) Delete(scope, tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	token, ok := m.db.tokens[string(tokenHash[:])]
+	if !ok || token.Scope != scope {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.tokens, string(tokenHash[:]))
+	return nil
+}
+
+// GetSessionsForUser lists the unexpired authentication tokens of a user as sessions, the most recently used first.
+func (m memoryTokenModel) GetSessionsForUser(userID int64, currentPlaintext string) ([]*Session, error) {
+	currentHash := sha256.Sum256([]byte(currentPlaintext))
+
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	sessions := []*Session{}
+	for key, token := range m.db.tokens {
+		if token.UserID != userID || token.Scope != ScopeAuthentication || !token.Expiry.After(time.Now()) {
+			continue
+		}
+		sessions = append(sessions, &Session{
+			ID:         token.ID,
+			CreatedAt:  token.CreatedAt,
+			LastUsedAt: token.LastUsedAt,
+			Expiry:     token.Expiry,
+			IP:         token.IP,
+			UserAgent:  token.UserAgent,
+			Current:    key == string(currentHash[:]),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if !a.LastUsedAt.Equal(b.LastUsedAt) {
+			return a.LastUsedAt.After(b.LastUsedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	return sessions, nil
+}
+
+// DeleteSession revokes a single session of a user.
+func (m memoryTokenModel) DeleteSession(userID, id int64) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	for key, token := range m.db.tokens {
+		if token.ID == id && token.UserID == userID && token.Scope == ScopeAuthentication {
+			delete(m.db.tokens, key)
+			return nil
+		}
+	}
+
+	return ErrRecordNotFound
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestUser saves an activated user with the given email.
+func newTestUser(t *testing.T, models Models, email string) *User {
+	t.Helper()
+
+	user := &User{Name: "Test User", Email: email, Activated: true}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestMemoryGetForToken(t *testing.T) {
+	models := NewMemoryModels()
+	user := newTestUser(t, models, "alice@example.com")
+
+	token, err := models.Tokens.New(user.ID, time.Hour, ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := models.Tokens.New(user.ID, -time.Hour, ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := models.Users.GetForToken(ScopeAuthentication, token.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("got user %d; want %d", got.ID, user.ID)
+	}
+	if !got.TokenLastUsedAt.Equal(token.LastUsedAt) {
+		t.Errorf("got token last used at %v; want %v", got.TokenLastUsedAt, token.LastUsedAt)
+	}
+
+	for _, tt := range []struct {
+		name, scope, plaintext string
+	}{
+		{"expired", ScopeAuthentication, expired.Plaintext},
+		{"other scope", ScopeActivation, token.Plaintext},
+		{"unknown", ScopeAuthentication, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"},
+	} {
+		if _, err := models.Users.GetForToken(tt.scope, tt.plaintext); !errors.Is(err, ErrRecordNotFound) {
+			t.Errorf("%s: got error %v; want ErrRecordNotFound", tt.name, err)
+		}
+	}
+}
+
+func TestMemorySessions(t *testing.T) {
+	models := NewMemoryModels()
+	alice := newTestUser(t, models, "alice@example.com")
+	bob := newTestUser(t, models, "bob@example.com")
+
+	laptop, err := models.Tokens.NewSession(alice.ID, time.Hour, "192.0.2.1", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := models.Tokens.NewSession(alice.ID, time.Hour, "192.0.2.2", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Tokens.New(alice.ID, time.Hour, ScopeActivation); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := models.Tokens.GetSessionsForUser(alice.ID, laptop.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions; want 2", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID == laptop.ID) {
+			t.Errorf("session %d (%s) has current %v", session.ID, session.UserAgent, session.Current)
+		}
+	}
+
+	// A user may only revoke their own sessions.
+	if err := models.Tokens.DeleteSession(bob.ID, phone.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("got error %v revoking another user's session; want ErrRecordNotFound", err)
+	}
+	if err := models.Tokens.DeleteSession(alice.ID, phone.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Users.GetForToken(ScopeAuthentication, phone.Plaintext); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("got error %v authenticating with a revoked session; want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"crypto/sha256"
+	"strings"
+	"time"
+)
+
+// memoryUserModel is the in-memory counterpart of UserModel.
+type memoryUserModel struct {
+	db *memoryDB
+}
+
+// copyUser returns a copy of user holding only what the users table stores: the plaintext password is dropped.
+func copyUser(user *User) *User {
+	c := *user
+	c.Password = password{hash: append([]byte(nil), user.Password.hash...)}
+	return &c
+}
+
+// emailTaken reports whether another user already holds email. The email column is citext, so the comparison is
+// case-insensitive. The caller must hold the lock.
+func (m memoryUserModel) emailTaken(email string, exceptID int64) bool {
+	for _, user := range m.db.users {
+		if user.ID != exceptID && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Insert adds a user, returning ErrDuplicatedEmail if the email address is already registered.
+func (m memoryUserModel) Insert(user *User) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if m.emailTaken(user.Email, 0) {
+		return ErrDuplicatedEmail
+	}
+
+	user.ID = m.db.nextID("users")
+	user.CreatedAt = m.db.now()
+	user.Version = 1
+
+	m.db.users[user.ID] = copyUser(user)
+	return nil
+}
+
+// GetByEmail returns the user for a given email address.
+func (m memoryUserModel) GetByEmail(email string) (*User, error) {
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	for _, user := range m.db.users {
+		if strings.EqualFold(user.Email, email) {
+			return copyUser(user), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+// Update replaces a user provided the version is current and the email address is not held by another user.
+func (m memoryUserModel) Update(user *User) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if m.emailTaken(user.Email, user.ID) {
+		return ErrDuplicatedEmail
+	}
+
+	stored, ok := m.db.users[user.ID]
+	if !ok || stored.Version != user.Version {
+		return ErrEditConflict
+	}
+
+	user.Version++
+	updated := copyUser(user)
+	updated.CreatedAt = stored.CreatedAt
+	m.db.users[user.ID] = updated
+
+	return nil
+}
+
+// GetForToken returns the user holding an unexpired token with the given scope.
+func (m memoryUserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	m.db.mu.RLock()
+	defer m.db.mu.RUnlock()
+
+	token, ok := m.db.tokens[string(tokenHash[:])]
+	if !ok || token.Scope != tokenScope || !token.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	user, ok := m.db.users[token.UserID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	c := copyUser(user)
+	c.TokenLastUsedAt = token.LastUsedAt
+	return c, nil
+}
+
+// DeleteUnactivated removes the accounts registered before the given time which were never activated and hold no
+// unexpired activation token, see UserModel.DeleteUnactivated.
+func (m memoryUserModel) DeleteUnactivated(before time.Time) (int64, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	pending := make(map[int64]bool)
+	for _, token := range m.db.tokens {
+		if token.Scope == ScopeActivation && token.Expiry.After(time.Now()) {
+			pending[token.UserID] = true
+		}
+	}
+
+	var deleted int64
+	for id, user := range m.db.users {
+		if !user.Activated && user.CreatedAt.Before(before) && !pending[id] {
+			m.db.deleteUser(id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
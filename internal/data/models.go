@@ -1,57 +1,205 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"time"
 )
 
-// Models acts as a container to wrap distinct models encapsulating the model definitions.
+// Models acts as a container to wrap distinct models encapsulating the model definitions. Each model is held behind
+// an interface so the PostgreSQL models may be swapped for the in-memory backend (see NewMemoryModels).
 type Models struct {
-	Movies      MovieModel
-	Users       UserModel
-	Tokens      TokenModel
-	Permissions PermissionModel
+	Movies      MovieStore
+	Users       UserStore
+	Tokens      TokenStore
+	Permissions PermissionStore
+	Revisions   RevisionStore
+	People      PersonStore
+	Credits     CreditStore
+	Ratings     RatingStore
+	Lists       ListStore
+	Genres      GenreStore
+	Titles      TitleStore
 }
 
 // NewModels returns an instance of Models which holds all our data models.
 func NewModels(db *sql.DB) Models {
 	return Models{
 		Movies:      MovieModel{DB: db},
-		Users:       UserModel{DB: db},
+		Users:       &UserModel{DB: db},
 		Tokens:      TokenModel{DB: db},
 		Permissions: PermissionModel{DB: db},
+		Revisions:   RevisionModel{DB: db},
+		People:      PersonModel{DB: db},
+		Credits:     CreditModel{DB: db},
+		Ratings:     RatingModel{DB: db},
+		Lists:       ListModel{DB: db},
+		Genres:      GenreModel{DB: db},
+		Titles:      TitleModel{DB: db},
 	}
 }
 
+// MovieStore describes the operations available on the movies table. Each method which saves a new version of a movie
+// records its revision too, attributed to userID (nil when unknown), so either both are saved or neither is.
+type MovieStore interface {
+	Insert(movie *Movie, userID *int64) error
+	InsertBatch(movies []*Movie, userID *int64) error
+	Get(id int64, fields ...string) (*Movie, error)
+	Update(movie *Movie, action string, userID *int64) error
+	Delete(movie *Movie, userID *int64) error
+	GetAll(criteria MovieCriteria, filters Filters, fields ...string) ([]*Movie, Metadata, error)
+	Facets(criteria MovieCriteria, names []string) (Facets, error)
+	Export(ctx context.Context, criteria MovieCriteria, filters Filters, fn func(*Movie) error) error
+	Restore(id int64, userID *int64) (*Movie, error)
+	PurgeDeleted(before time.Time) ([]int64, error)
+	FindDuplicates(movie *Movie) ([]*Movie, error)
+	SetPoster(id int64, poster string) (string, error)
+	AddPosterSize(id int64, poster, size string) error
+}
+
+// RevisionStore describes the operations available on the movie_revisions table.
+type RevisionStore interface {
+	Get(movieID int64, version int32) (*Revision, error)
+	GetAllForMovie(movieID int64, filters Filters) ([]*Revision, Metadata, error)
+}
+
+// PersonStore describes the operations available on the people table.
+type PersonStore interface {
+	Insert(person *Person) error
+	Get(id int64) (*Person, error)
+	Update(person *Person) error
+	Delete(id int64) error
+	GetAll(name string, filters Filters) ([]*Person, Metadata, error)
+}
+
+// CreditStore describes the operations available on the movie_credits table.
+type CreditStore interface {
+	Insert(credit *Credit) error
+	Delete(movieID, id int64) error
+	GetAllForMovie(movieID int64) ([]*Credit, error)
+	GetAllForMovies(movieIDs []int64) (map[int64][]*Credit, error)
+}
+
+// RatingStore describes the operations available on the ratings table.
+type RatingStore interface {
+	Insert(rating *Rating) error
+	GetForUser(movieID, userID int64) (*Rating, error)
+	Update(rating *Rating) error
+	Delete(rating *Rating) error
+	GetAllForMovie(movieID int64, filters Filters) ([]*Rating, Metadata, error)
+	GetLatestForMovies(movieIDs []int64, limit int) (map[int64][]*Rating, error)
+}
+
+// TitleStore describes the operations available on the movie_titles table.
+type TitleStore interface {
+	Insert(title *Title) error
+	Delete(movieID, id int64) error
+	GetAllForMovie(movieID int64) ([]*Title, error)
+	GetAllForMovies(movieIDs []int64) (map[int64][]*Title, error)
+}
+
+// GenreStore describes the operations available on the genres table.
+type GenreStore interface {
+	Insert(genre *Genre) error
+	Get(id int64) (*Genre, error)
+	GetAll() ([]*Genre, error)
+	Update(genre *Genre, userID *int64) error
+	Delete(id int64) error
+}
+
+// ListStore describes the operations available on the lists and list_entries tables.
+type ListStore interface {
+	Insert(list *List) error
+	Get(id int64) (*List, error)
+	GetForShareToken(plaintext string) (*List, error)
+	GetAllForUser(userID int64, filters Filters) ([]*List, Metadata, error)
+	Update(list *List) error
+	Delete(id int64) error
+	GetEntries(listID int64) ([]*ListEntry, error)
+	AddEntry(entry *ListEntry) error
+	SetEntryNotes(listID, movieID int64, notes string) error
+	MoveEntry(listID, movieID int64, position int32) error
+	RemoveEntry(listID, movieID int64) error
+}
+
+// UserStore describes the operations available on the users table.
+type UserStore interface {
+	Insert(user *User) error
+	GetByEmail(email string) (*User, error)
+	Update(user *User) error
+	GetForToken(tokenScope, tokenPlaintext string) (*User, error)
+	DeleteUnactivated(before time.Time) (int64, error)
+}
+
+// TokenStore describes the operations available on the tokens table.
+type TokenStore interface {
+	New(userID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(token *Token) error
+	DeleteAllForUser(scope string, userID int64) error
+	NewSession(userID int64, ttl time.Duration, ip, userAgent string) (*Token, error)
+	Touch(tokenPlaintext string) error
+	Delete(scope, tokenPlaintext string) error
+	GetSessionsForUser(userID int64, currentPlaintext string) ([]*Session, error)
+	DeleteSession(userID, id int64) error
+}
+
+// PermissionStore describes the operations available on the permissions and users_permissions tables.
+type PermissionStore interface {
+	GetAllForUser(userID int64) (Permissions, error)
+	AddForUser(userID int64, codes ...string) error
+}
+
 // Token allows a user to both Activate and Authenticate depending on scope.
 type Token struct {
-	Plaintext string    `json:"token"`
-	Hash      []byte    `json:"-"`
-	UserID    int64     `json:"-"` // references User.ID on Users table
-	Expiry    time.Time `json:"expiry"`
-	Scope     string    `json:"-"`
+	Plaintext  string    `json:"token"`
+	Hash       []byte    `json:"-"`
+	UserID     int64     `json:"-"` // references User.ID on Users table
+	Expiry     time.Time `json:"expiry"`
+	Scope      string    `json:"-"`
+	ID         int64     `json:"-"` // identifies an authentication token as a Session
+	CreatedAt  time.Time `json:"-"`
+	LastUsedAt time.Time `json:"-"`
+	IP         string    `json:"-"` // client address the token was issued to, empty outside of authentication
+	UserAgent  string    `json:"-"`
 }
 
 // Movie describes an individual film entry within the movies table.
 type Movie struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"-"`
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`
-	Runtime   Runtime   `json:"runtime,omitempty"` // declare in runtime.go
-	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version"`
+	ID            int64             `json:"id"`
+	CreatedAt     time.Time         `json:"-"`
+	Title         string            `json:"title"`
+	TitleLocale   string            `json:"title_locale,omitempty"` // language tag of Title when an alternate title was chosen
+	Year          int32             `json:"year,omitempty"`
+	Runtime       Runtime           `json:"runtime,omitempty"` // declare in runtime.go
+	RuntimeFormat RuntimeFormat     `json:"-"`                 // format Runtime is written in, the default when empty
+	Genres        []string          `json:"genres,omitempty"`
+	ExternalIDs   ExternalIDs       `json:"external_ids,omitempty"` // IDs in other catalogues by source, each unique
+	Version       int32             `json:"version"`
+	Rank          float32           `json:"rank,omitempty"`       // relevance to the title search, only set by GetAll
+	Headline      string            `json:"headline,omitempty"`   // title with search matches highlighted, only set by GetAll
+	Similarity    float32           `json:"similarity,omitempty"` // similarity to MovieCriteria.Similar, only set by GetAll
+	DeletedAt     *time.Time        `json:"deleted_at,omitempty"` // set while the movie is in the trash
+	Rating        float32           `json:"rating,omitempty"`     // average score of the ratings, 0 while unrated
+	RatingCount   int32             `json:"rating_count"`
+	Poster        string            `json:"-"`                 // file name of the current poster upload, empty without one
+	PosterSizes   []string          `json:"-"`                 // sizes of the poster stored so far, see PosterKeys
+	Posters       map[string]string `json:"posters,omitempty"` // poster URL by size, filled in by the API
+	Fields        []string          `json:"-"`                 // fields the movie is written with, all when empty
+	Credits       []*Credit         `json:"credits,omitempty"` // included on request, filled in by the API
+	Ratings       []*Rating         `json:"ratings,omitempty"` // most recent IncludedRatings, included on request
+	Titles        []*Title          `json:"titles,omitempty"`  // alternate titles, included on request
 }
 
 // User describes a single user within the users table.
 type User struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Activated bool      `json:"activated"`
-	Version   int       `json:"version"`
+	ID              int64     `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	Name            string    `json:"name"`
+	Email           string    `json:"email"`
+	Password        password  `json:"-"`
+	Activated       bool      `json:"activated"`
+	Version         int       `json:"version"`
+	TokenLastUsedAt time.Time `json:"-"` // last use of the token the user was read with, only set by GetForToken
 }
 
 var AnonymousUser = &User{}
@@ -20,47 +20,117 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// BatchError identifies the movie which caused a batch to be rejected, for errors due to the movie itself such as
+// ErrDuplicateExternalID. Nothing in the batch has been saved.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
 // Insert creates a new Movie within the MovieModel database.
-func (m MovieModel) Insert(movie *Movie) error {
+func (m MovieModel) Insert(movie *Movie, userID *int64) error {
 	query := `
-	INSERT INTO movies (title, year, runtime, genres)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO movies (title, year, runtime, genres, external_ids)
+	VALUES ($1, $2, $3, $4, $5)
 	RETURNING id, created_at, version`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	// pg.Array required
-	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ExternalIDs}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	if err != nil {
+		return externalIDError(err)
+	}
+
+	err = insertRevision(ctx, tx, movie, RevisionInsert, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// InsertBatch adds several movies within a single transaction, so either all of them are saved or none are. The ID,
+// CreatedAt and Version fields of each movie are populated as with Insert. When a movie is rejected the error is a
+// *BatchError giving its position; errors which say nothing about the movie, such as a lost connection, are returned
+// as they are.
+func (m MovieModel) InsertBatch(movies []*Movie, userID *int64) error {
+	query := `
+	INSERT INTO movies (title, year, runtime, genres, external_ids)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, movie := range movies {
+		args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ExternalIDs}
+		err = stmt.QueryRowContext(ctx, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+		if err != nil {
+			err = externalIDError(err)
+			if errors.Is(err, ErrDuplicateExternalID) {
+				return &BatchError{Index: i, Err: err}
+			}
+			return err
+		}
+
+		err = insertRevision(ctx, tx, movie, RevisionInsert, userID)
+		if err != nil {
+			return err
+		}
+	}
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	return tx.Commit()
 }
 
-// Get retrieves a single movie from the MovieModel database.
-func (m MovieModel) Get(id int64) (*Movie, error) {
+// Get retrieves a single movie from the MovieModel database. When fields are given only the columns needed to
+// write them are read, see MovieFields.
+func (m MovieModel) Get(id int64, fields ...string) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `
-	SELECT id, created_at, title, year, runtime, genres, version
+	columns, dest := selectColumns(movieColumns, fields)
+	query := fmt.Sprintf(`
+	SELECT %s
 	FROM movies
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NULL`, columns)
 
 	var movie Movie
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.Title,
-		&movie.Year,
-		&movie.Runtime,
-		pq.Array(&movie.Genres),
-		&movie.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(dest(&movie)...)
 
 	if err != nil {
 		switch {
@@ -75,12 +145,13 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 
 }
 
-// Update a single Movie, supports parital updates
-func (m MovieModel) Update(movie *Movie) error {
+// Update a single Movie, supports parital updates. The revision is recorded with the given action, as a revert saves
+// a new version in the same way as an update.
+func (m MovieModel) Update(movie *Movie, action string, userID *int64) error {
 	query := `
 	UPDATE movies
-	SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-	WHERE id = $5 AND version = $6
+	SET title = $1, year = $2, runtime = $3, genres = $4, external_ids = $5, version = version + 1
+	WHERE id = $6 AND version = $7 AND deleted_at IS NULL
 	RETURNING version`
 
 	args := []interface{}{
@@ -88,6 +159,7 @@ func (m MovieModel) Update(movie *Movie) error {
 		movie.Year,
 		movie.Runtime,
 		pq.Array(movie.Genres),
+		movie.ExternalIDs,
 		movie.ID,
 		movie.Version,
 	}
@@ -95,95 +167,152 @@ func (m MovieModel) Update(movie *Movie) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
 		default:
-			return err
+			return externalIDError(err)
 		}
 	}
-	return nil
-}
 
-// Delete removes a single movie from the MovieModel database
-func (m MovieModel) Delete(id int64) error {
-	if id < 1 {
-		return ErrRecordNotFound
+	err = insertRevision(ctx, tx, movie, action, userID)
+	if err != nil {
+		return err
 	}
 
+	return tx.Commit()
+}
+
+// Delete moves a single movie to the trash, provided it has not changed since it was read. The row is kept, with
+// deleted_at set, until PurgeDeleted removes it. The movie's Version and DeletedAt are updated to match.
+func (m MovieModel) Delete(movie *Movie, userID *int64) error {
 	query := `
-	DELETE FROM movies
-	WHERE id = $1
-	`
+	UPDATE movies
+	SET deleted_at = NOW(), version = version + 1
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+	RETURNING version, deleted_at`
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	err = tx.QueryRowContext(ctx, query, movie.ID, movie.Version).Scan(&movie.Version, &movie.DeletedAt)
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	err = insertRevision(ctx, tx, movie, RevisionDelete, userID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-// GetAll retrieves all movies from the database which match certain criteria
-// GetAll retrieves all movies from the database which match certain criteria
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+// GetAll retrieves all movies from the database which match certain criteria. Pages are selected by OFFSET or, when
+// filters carries a cursor, by seeking past the cursor row (keyset pagination) which stays fast on deep pages. When
+// fields are given only the columns needed to write them are read, along with the values sorted by for the cursors.
+func (m MovieModel) GetAll(criteria MovieCriteria, filters Filters, fields ...string) ([]*Movie, Metadata, error) {
+	keys, err := filters.sortKeys()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	after, err := filters.cursor(keys)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	var q filterQuery
+	search, err := criteria.apply(&q)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	expr := func(column string) string { return sortExpression(column, search) }
+
+	// The total is opt-in as it requires counting every matching row, not just those on the page.
+	total := "0"
+	if filters.IncludeTotal {
+		total = fmt.Sprintf("(SELECT count(*) FROM movies WHERE %s)", q.clause())
+	}
+
+	order := keys
 
-	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
+	var page string
+	if after != nil {
+		pivot, err := after.pivot(keys)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		args := make([]string, len(keys))
+		for i, key := range keys {
+			args[i] = q.arg(sortValue(pivot, key.column))
+		}
+		q.where(keysetCondition(keys, expr, after.Backward, args))
+
+		// Walk backwards from the cursor, paginate restores the requested order.
+		if after.Backward {
+			order = reverseKeys(keys)
+		}
+
+		page = fmt.Sprintf("LIMIT %s", q.arg(filters.limit()+1))
+	} else {
+		page = fmt.Sprintf("LIMIT %s OFFSET %s", q.arg(filters.limit()+1), q.arg(filters.offset()))
+	}
+
+	if len(fields) > 0 {
+		fields = append([]string{}, fields...)
+		for _, key := range keys {
+			fields = append(fields, sortField(key.column))
+		}
+		// The arguments of the similarity score are only referred to by its expression, so it is always selected.
+		if criteria.Similar != nil {
+			fields = append(fields, "similarity")
+		}
+	}
+	columns, dest := selectColumns(append(append([]movieColumn{}, movieColumns...), searchColumns(search)...), fields)
+
+	query := fmt.Sprintf(`SELECT %s, %s
 	FROM movies
-	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) or $1 = '')
-	AND (genres @> $2 OR $2 = '{}')
-	ORDER BY %s %s, id ASC
-	LIMIT $3 OFFSET $4
-	`,
-		filters.sortColumn(), filters.sortDirection())
-
-	//query := `SELECT id, created_at, title, year, runtime, genres, version
-	//FROM movies
-	//WHERE (LOWER(title) = LOWER($1) OR $1 = '')
-	//AND (genres @> $2 OR $2 = '{}')
-	//ORDER BY id`
+	WHERE %s
+	ORDER BY %s
+	%s`,
+		total, columns, q.clause(), orderByKeys(order, expr), page)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{title, pq.Array(genres), filters.limit(), filters.offset()}
-
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	rows, err := m.DB.QueryContext(ctx, query, q.args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 
 	defer rows.Close()
 
-	// movies := []*Movie{} => []
-	//var movies []*Movie => {}
 	totalRecords := 0
 	var movies []*Movie // Have to account for nil pointer now in main handler.
 
 	for rows.Next() {
 		var movie Movie
-		err := rows.Scan(
-			&totalRecords,
-			&movie.ID,
-			&movie.CreatedAt,
-			&movie.Title,
-			&movie.Year,
-			&movie.Runtime,
-			pq.Array(&movie.Genres),
-			&movie.Version)
+		err := rows.Scan(append([]interface{}{&totalRecords}, dest(&movie)...)...)
 
 		if err != nil {
 			return nil, Metadata{}, err
@@ -195,10 +324,115 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	movies, metadata := paginate(movies, totalRecords, filters, keys, after)
 	return movies, metadata, nil
 }
 
+// Restore takes a movie out of the trash, returning the restored movie.
+func (m MovieModel) Restore(id int64, userID *int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	UPDATE movies
+	SET deleted_at = NULL, version = version + 1
+	WHERE id = $1 AND deleted_at IS NOT NULL
+	RETURNING id, created_at, title, year, runtime, genres, version, rating_avg, rating_count, poster, poster_sizes,
+	          external_ids`
+
+	var movie Movie
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.Title,
+		&movie.Year,
+		&movie.Runtime,
+		pq.Array(&movie.Genres),
+		&movie.Version,
+		&movie.Rating,
+		&movie.RatingCount,
+		&movie.Poster,
+		pq.Array(&movie.PosterSizes),
+		&movie.ExternalIDs,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = insertRevision(ctx, tx, &movie, RevisionRestore, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &movie, nil
+}
+
+// PurgeDeleted permanently removes movies which were moved to the trash before the given time, returning the IDs of
+// those removed so that files stored for them, such as posters, can be removed too.
+func (m MovieModel) PurgeDeleted(before time.Time) ([]int64, error) {
+	query := `
+	DELETE FROM movies
+	WHERE deleted_at < $1
+	RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// sortExpression returns the ORDER BY expression for a sort column. Relevance orders by the rank of the title search,
+// similarity by the similarity score and rating by the stored average.
+func sortExpression(column string, search titleSearch) string {
+	switch column {
+	case "relevance":
+		return search.rank
+	case "similarity":
+		return search.similarity
+	case "rating":
+		return "rating_avg"
+	default:
+		return column
+	}
+}
+
 // ValidateMovie acts as a validator ensuring the parameters passed to the
 // containing function are appropriate/valid.
 func ValidateMovie(v *validator.Validator, movie *Movie) {
@@ -217,4 +451,7 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
 	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
 
+	ValidateExternalIDs(v, movie.ExternalIDs)
+
 }
+
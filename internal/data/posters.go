@@ -0,0 +1,177 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register the decoders used by image.DecodeConfig
+	_ "image/png"
+	"movieDB/internal/validator"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// PosterOriginal is the size of a poster as it was uploaded.
+const PosterOriginal = "original"
+
+// Limits on uploaded posters. Dimensions are in pixels.
+const (
+	PosterMaxBytes  = 5 << 20
+	PosterMinSize   = 200
+	PosterMaxWidth  = 4000
+	PosterMaxHeight = 6000
+)
+
+// posterTypes maps the sniffed content types accepted for posters to the extension they are stored with.
+var posterTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// PosterWidths holds the width in pixels of each thumbnail size generated from an uploaded poster.
+var PosterWidths = map[string]int{"small": 185, "medium": 500}
+
+// NewPosterName returns a random name for a new poster upload with the given extension. Every upload gets a fresh
+// name so that its files can be cached indefinitely and never collide with those of the upload it replaces.
+func NewPosterName(ext string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + ext, nil
+}
+
+// PosterPrefix returns the blob key prefix beneath which every poster of a movie is stored.
+func PosterPrefix(movieID int64) string {
+	return fmt.Sprintf("posters/%d/", movieID)
+}
+
+// PosterUploadPrefix returns the blob key prefix of a single poster upload, see Movie.Poster.
+func PosterUploadPrefix(movieID int64, poster string) string {
+	return PosterPrefix(movieID) + strings.TrimSuffix(poster, path.Ext(poster)) + "/"
+}
+
+// PosterKey returns the blob key of a poster at the given size. The original keeps the format it was uploaded in,
+// thumbnails are always JPEG.
+func PosterKey(movieID int64, poster, size string) string {
+	ext := ".jpg"
+	if size == PosterOriginal {
+		ext = path.Ext(poster)
+	}
+	return PosterUploadPrefix(movieID, poster) + size + ext
+}
+
+// PosterKeys returns the blob key of each size of the movie's poster stored so far, keyed by size. It is empty when
+// the movie has no poster.
+func (m *Movie) PosterKeys() map[string]string {
+	keys := make(map[string]string, len(m.PosterSizes))
+	if m.Poster == "" {
+		return keys
+	}
+
+	for _, size := range m.PosterSizes {
+		keys[size] = PosterKey(m.ID, m.Poster, size)
+	}
+	return keys
+}
+
+// SetPoster replaces the poster of a live movie with a new upload, or removes it when poster is empty, returning the
+// poster it replaced so that its files can be removed. Only the original size is recorded for a new upload, each
+// thumbnail is added by AddPosterSize once stored. The movie's version is unchanged as the poster is not part of
+// the movie's revisions.
+func (m MovieModel) SetPoster(id int64, poster string) (string, error) {
+	if id < 1 {
+		return "", ErrRecordNotFound
+	}
+
+	query := `
+	UPDATE movies m
+	SET poster = $2, poster_sizes = CASE WHEN $2 = '' THEN '{}'::text[] ELSE ARRAY[$3::text] END
+	FROM (SELECT id, poster FROM movies WHERE id = $1 FOR UPDATE) previous
+	WHERE m.id = previous.id AND m.deleted_at IS NULL
+	RETURNING previous.poster`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var previous string
+	err := m.DB.QueryRowContext(ctx, query, id, poster, PosterOriginal).Scan(&previous)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return previous, nil
+}
+
+// AddPosterSize records that a size of the movie's poster has been stored. ErrEditConflict is returned if the poster
+// has since been replaced or removed, in which case the caller should remove the file it stored.
+func (m MovieModel) AddPosterSize(id int64, poster, size string) error {
+	query := `
+	UPDATE movies
+	SET poster_sizes = array_append(poster_sizes, $3::text)
+	WHERE id = $1 AND poster = $2 AND NOT poster_sizes @> ARRAY[$3::text]`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, poster, size)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// ValidatePoster checks the contents of an uploaded poster, returning the file extension to store it with. The type
+// is sniffed from the contents rather than trusted from the client, and only the image header is decoded to check
+// the dimensions so that oversized images are rejected before any pixels are allocated.
+func ValidatePoster(v *validator.Validator, content []byte) string {
+	switch {
+	case len(content) == 0:
+		v.AddError("poster", "must be provided")
+		return ""
+	case len(content) > PosterMaxBytes:
+		v.AddError("poster", fmt.Sprintf("must not be larger than %d bytes", PosterMaxBytes))
+		return ""
+	}
+
+	ext, ok := posterTypes[http.DetectContentType(content)]
+	if !ok {
+		v.AddError("poster", "must be a JPEG or PNG image")
+		return ""
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		v.AddError("poster", "must be a valid image")
+		return ""
+	}
+
+	v.Check(config.Width >= PosterMinSize && config.Height >= PosterMinSize, "poster",
+		fmt.Sprintf("must be at least %d pixels wide and high", PosterMinSize))
+	v.Check(config.Width <= PosterMaxWidth && config.Height <= PosterMaxHeight, "poster",
+		fmt.Sprintf("must not be larger than %dx%d pixels", PosterMaxWidth, PosterMaxHeight))
+
+	return ext
+}
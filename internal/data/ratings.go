@@ -0,0 +1,274 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"time"
+)
+
+// RatingMax is the highest score a rating may give, the lowest being 1.
+const RatingMax = 10
+
+// ErrDuplicateRating is returned when a user rates a movie they have already rated.
+var ErrDuplicateRating = errors.New("duplicate rating")
+
+// Rating is a single user's score for a movie, optionally with a written review.
+type Rating struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movie_id"`
+	UserID    int64     `json:"user_id"`
+	Score     int32     `json:"score"`
+	Review    string    `json:"review,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int32     `json:"version"`
+}
+
+// RatingModel holds the database pool for the ratings table. Each change to a rating also adjusts the rating_count,
+// rating_total and rating_avg columns of the movie within the same transaction, so the aggregates shown on movies
+// are always current.
+type RatingModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a rating, returning ErrDuplicateRating if the user has already rated the movie.
+func (m RatingModel) Insert(rating *Rating) error {
+	query := `
+	INSERT INTO ratings (movie_id, user_id, score, review)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	args := []interface{}{rating.MovieID, rating.UserID, rating.Score, rating.Review}
+
+	return m.withTx(func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&rating.ID, &rating.CreatedAt, &rating.Version)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "ratings_movie_id_user_id_key"`:
+				return ErrDuplicateRating
+			default:
+				return err
+			}
+		}
+		return adjustRating(ctx, tx, rating.MovieID, 1, rating.Score)
+	})
+}
+
+// GetForUser retrieves the rating a user gave a movie.
+func (m RatingModel) GetForUser(movieID, userID int64) (*Rating, error) {
+	query := `
+	SELECT id, movie_id, user_id, score, review, created_at, version
+	FROM ratings
+	WHERE movie_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rating Rating
+	err := m.DB.QueryRowContext(ctx, query, movieID, userID).Scan(
+		&rating.ID,
+		&rating.MovieID,
+		&rating.UserID,
+		&rating.Score,
+		&rating.Review,
+		&rating.CreatedAt,
+		&rating.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rating, nil
+}
+
+// Update saves a rating provided the version has not changed since it was read, otherwise ErrEditConflict.
+func (m RatingModel) Update(rating *Rating) error {
+	query := `
+	UPDATE ratings new
+	SET score = $1, review = $2, version = new.version + 1
+	FROM ratings old
+	WHERE new.id = old.id AND new.id = $3 AND new.version = $4
+	RETURNING new.version, old.score`
+
+	args := []interface{}{rating.Score, rating.Review, rating.ID, rating.Version}
+
+	return m.withTx(func(ctx context.Context, tx *sql.Tx) error {
+		var oldScore int32
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&rating.Version, &oldScore)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrEditConflict
+			default:
+				return err
+			}
+		}
+		return adjustRating(ctx, tx, rating.MovieID, 0, rating.Score-oldScore)
+	})
+}
+
+// Delete removes a rating provided the version has not changed since it was read, otherwise ErrEditConflict.
+func (m RatingModel) Delete(rating *Rating) error {
+	query := `
+	DELETE FROM ratings
+	WHERE id = $1 AND version = $2
+	RETURNING score`
+
+	return m.withTx(func(ctx context.Context, tx *sql.Tx) error {
+		var score int32
+		err := tx.QueryRowContext(ctx, query, rating.ID, rating.Version).Scan(&score)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrEditConflict
+			default:
+				return err
+			}
+		}
+		return adjustRating(ctx, tx, rating.MovieID, -1, -score)
+	})
+}
+
+// GetAllForMovie returns a page of the ratings of a movie.
+func (m RatingModel) GetAllForMovie(movieID int64, filters Filters) ([]*Rating, Metadata, error) {
+	order, err := filters.orderBy()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, movie_id, user_id, score, review, created_at, version
+	FROM ratings
+	WHERE movie_id = $1
+	ORDER BY %s
+	LIMIT $2 OFFSET $3`, order)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	ratings := []*Rating{}
+
+	for rows.Next() {
+		var rating Rating
+		err := rows.Scan(
+			&totalRecords,
+			&rating.ID,
+			&rating.MovieID,
+			&rating.UserID,
+			&rating.Score,
+			&rating.Review,
+			&rating.CreatedAt,
+			&rating.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		ratings = append(ratings, &rating)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return ratings, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// GetLatestForMovies retrieves up to limit of the most recent ratings of each of several movies, keyed by movie ID.
+// Movies without ratings are absent from the map.
+func (m RatingModel) GetLatestForMovies(movieIDs []int64, limit int) (map[int64][]*Rating, error) {
+	query := `
+	SELECT id, movie_id, user_id, score, review, created_at, version
+	FROM (
+		SELECT *, row_number() OVER (PARTITION BY movie_id ORDER BY created_at DESC, id DESC) AS position
+		FROM ratings
+		WHERE movie_id = ANY($1)
+	) latest
+	WHERE position <= $2
+	ORDER BY movie_id, position`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := make(map[int64][]*Rating)
+	for rows.Next() {
+		var rating Rating
+		err := rows.Scan(
+			&rating.ID,
+			&rating.MovieID,
+			&rating.UserID,
+			&rating.Score,
+			&rating.Review,
+			&rating.CreatedAt,
+			&rating.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		ratings[rating.MovieID] = append(ratings[rating.MovieID], &rating)
+	}
+
+	return ratings, rows.Err()
+}
+
+// withTx runs fn within a transaction, committing if fn succeeds.
+func (m RatingModel) withTx(fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// adjustRating applies a change in the number and total of a movie's ratings to its aggregate columns. The
+// increments are relative to the row as it stands when the UPDATE takes its lock, so concurrent ratings of the same
+// movie are not lost. The movie version is left alone, rating a movie is not an edit.
+func adjustRating(ctx context.Context, tx *sql.Tx, movieID int64, count, total int32) error {
+	query := `
+	UPDATE movies
+	SET rating_count = rating_count + $2,
+	    rating_total = rating_total + $3,
+	    rating_avg = CASE
+	        WHEN rating_count + $2 = 0 THEN 0
+	        ELSE round((rating_total + $3)::numeric / (rating_count + $2), 2)
+	    END
+	WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, movieID, count, total)
+	return err
+}
+
+// ValidateRating checks the fields of a rating before it is saved.
+func ValidateRating(v *validator.Validator, rating *Rating) {
+	v.Check(rating.Score >= 1 && rating.Score <= RatingMax, "score", fmt.Sprintf("must be between 1 and %d", RatingMax))
+	v.Check(len(rating.Review) <= 10_000, "review", "must not be longer than 10000 bytes")
+}
@@ -0,0 +1,200 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Revision actions, recording what produced each version of a movie.
+const (
+	RevisionInsert  = "insert"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert  = "revert"
+)
+
+// MovieSnapshot holds the editable fields of a movie as they were at a single version.
+type MovieSnapshot struct {
+	Title       string      `json:"title"`
+	Year        int32       `json:"year"`
+	Runtime     Runtime     `json:"runtime"`
+	Genres      []string    `json:"genres"`
+	ExternalIDs ExternalIDs `json:"external_ids"`
+}
+
+// Revision describes a single entry within the movie_revisions table.
+type Revision struct {
+	ID        int64         `json:"id"`
+	MovieID   int64         `json:"movie_id"`
+	Version   int32         `json:"version"`
+	Action    string        `json:"action"`
+	Snapshot  MovieSnapshot `json:"snapshot"`
+	UserID    *int64        `json:"user_id"` // nil when the acting user is unknown or has since been deleted
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// FieldChange describes a single field which differs between two revisions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// RevisionModel holds the database pool for the movie_revisions table.
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// SnapshotOf captures the editable fields of movie.
+func SnapshotOf(movie *Movie) MovieSnapshot {
+	return MovieSnapshot{
+		Title:       movie.Title,
+		Year:        movie.Year,
+		Runtime:     movie.Runtime,
+		Genres:      copyStrings(movie.Genres),
+		ExternalIDs: copyExternalIDs(movie.ExternalIDs),
+	}
+}
+
+// Apply copies the snapshot onto movie, leaving its identity and version untouched.
+func (s MovieSnapshot) Apply(movie *Movie) {
+	movie.Title = s.Title
+	movie.Year = s.Year
+	movie.Runtime = s.Runtime
+	movie.Genres = copyStrings(s.Genres)
+	movie.ExternalIDs = copyExternalIDs(s.ExternalIDs)
+}
+
+// Diff lists the fields which differ between s and to, in the order they appear in MovieSnapshot.
+func (s MovieSnapshot) Diff(to MovieSnapshot) []FieldChange {
+	changes := []FieldChange{}
+
+	a, b := reflect.ValueOf(s), reflect.ValueOf(to)
+	for i := 0; i < a.NumField(); i++ {
+		if reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field: a.Type().Field(i).Tag.Get("json"),
+			From:  a.Field(i).Interface(),
+			To:    b.Field(i).Interface(),
+		})
+	}
+
+	return changes
+}
+
+// insertRevision records the version movie has reached as a result of action, within the transaction making the
+// change so that a version is never saved without its revision.
+func insertRevision(ctx context.Context, tx *sql.Tx, movie *Movie, action string, userID *int64) error {
+	snapshot, err := json.Marshal(SnapshotOf(movie))
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO movie_revisions (movie_id, version, action, snapshot, user_id)
+	VALUES ($1, $2, $3, $4, $5)`
+
+	_, err = tx.ExecContext(ctx, query, movie.ID, movie.Version, action, snapshot, userID)
+	return err
+}
+
+// Get returns the revision recording the given version of a movie.
+func (m RevisionModel) Get(movieID int64, version int32) (*Revision, error) {
+	query := `
+	SELECT id, movie_id, version, action, snapshot, user_id, created_at
+	FROM movie_revisions
+	WHERE movie_id = $1 AND version = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	revision, err := scanRevision(m.DB.QueryRowContext(ctx, query, movieID, version))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return revision, nil
+}
+
+// GetAllForMovie returns a page of the revisions of a movie, sorted by version.
+func (m RevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*Revision, Metadata, error) {
+	order, err := filters.orderBy()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, movie_id, version, action, snapshot, user_id, created_at
+	FROM movie_revisions
+	WHERE movie_id = $1
+	ORDER BY %s
+	LIMIT $2 OFFSET $3`, order)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		var revision *Revision
+		revision, err = scanRevision(rows, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return revisions, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// scanRevision scans a movie_revisions row, preceded by any extra destinations (such as a window count).
+func scanRevision(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*Revision, error) {
+	var (
+		revision Revision
+		snapshot []byte
+	)
+
+	dest := append(extra,
+		&revision.ID,
+		&revision.MovieID,
+		&revision.Version,
+		&revision.Action,
+		&snapshot,
+		&revision.UserID,
+		&revision.CreatedAt,
+	)
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(snapshot, &revision.Snapshot); err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
@@ -3,6 +3,8 @@ package data
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -14,37 +16,147 @@ type Runtime int32
 // ErrInvalidRuntimeFormat => runtime formatting error
 var ErrInvalidRuntimeFormat = errors.New("invalid runtime format")
 
+// Errors for runtimes which are well formed but out of range.
+var (
+	ErrNegativeRuntime = errors.New("runtime must not be negative")
+	ErrRuntimeOverflow = fmt.Errorf("runtime must not be more than %d minutes", math.MaxInt32)
+)
+
+// RuntimeFormat is a way of writing a Runtime in JSON, chosen by the client for each request.
+type RuntimeFormat string
+
+// The formats a Runtime may be written in, each shown with a runtime of 107 minutes.
+const (
+	RuntimeFormatMins    RuntimeFormat = "mins"    // "107 mins", the default
+	RuntimeFormatMinutes RuntimeFormat = "minutes" // 107
+	RuntimeFormatHours   RuntimeFormat = "hm"      // "1h 47m"
+	RuntimeFormatISO8601 RuntimeFormat = "iso8601" // "PT1H47M"
+)
+
+// RuntimeFormats lists every RuntimeFormat, the default first.
+var RuntimeFormats = []RuntimeFormat{RuntimeFormatMins, RuntimeFormatMinutes, RuntimeFormatHours, RuntimeFormatISO8601}
+
+// Patterns of the runtimes accepted by ParseRuntime, see its documentation.
+var (
+	runtimeMinutesRX = regexp.MustCompile(`(?i)^([0-9]+)(?:\s*(?:mins?|minutes?))?$`)
+	runtimeHoursRX   = regexp.MustCompile(`(?i)^(?:([0-9]+)\s*h)?\s*(?:([0-9]+)\s*m)?$`)
+	runtimeISO8601RX = regexp.MustCompile(`(?i)^PT(?:([0-9]+)H)?(?:([0-9]+)M)?$`)
+)
+
 // MarshalJSON ensures the datatype Runtype int32 is Marshalled correctly.
 func (r Runtime) MarshalJSON() ([]byte, error) {
-	jsonValue := fmt.Sprintf("%d mins", r)
-	quotedJsonValue := strconv.Quote(jsonValue)
-	return []byte(quotedJsonValue), nil
+	return r.MarshalFormat(RuntimeFormatMins)
 }
 
-// UnmarshalJSON converts custom Runtime string to an int32
-// Example. Movie.Runtime = "103 Mins"
-// 1. Unquote the string "103 Mins", 2. Split by Whitespace
-// yielding []string{"103", "mins"}. Part[0]string="103"
-// 3. Convert string to an int32 value.
-func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
-	if err != nil {
-		return ErrInvalidRuntimeFormat
+// MarshalFormat writes the runtime as JSON in the given format. An unknown format is written as the default.
+func (r Runtime) MarshalFormat(format RuntimeFormat) ([]byte, error) {
+	if format == RuntimeFormatMinutes {
+		return []byte(strconv.FormatInt(int64(r), 10)), nil
 	}
+	return []byte(strconv.Quote(r.Text(format))), nil
+}
 
-	parts := strings.Split(unquotedJSONValue, " ")
+// Text returns the runtime written in the given format, without quotes. An unknown format is written as the default.
+func (r Runtime) Text(format RuntimeFormat) string {
+	hours, minutes := r/60, r%60
 
-	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeFormat
+	switch format {
+	case RuntimeFormatMinutes:
+		return strconv.FormatInt(int64(r), 10)
+	case RuntimeFormatHours:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("%dm", minutes)
+		case minutes == 0:
+			return fmt.Sprintf("%dh", hours)
+		default:
+			return fmt.Sprintf("%dh %dm", hours, minutes)
+		}
+	case RuntimeFormatISO8601:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("PT%dM", minutes)
+		case minutes == 0:
+			return fmt.Sprintf("PT%dH", hours)
+		default:
+			return fmt.Sprintf("PT%dH%dM", hours, minutes)
+		}
+	default:
+		return fmt.Sprintf("%d mins", r)
+	}
+}
+
+// UnmarshalJSON reads a runtime given either as a JSON number of minutes or as a string in any of the forms accepted
+// by ParseRuntime. A null leaves the runtime unchanged.
+func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
+	s := string(jsonValue)
+	if s == "null" {
+		return nil
 	}
 
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+	if strings.HasPrefix(s, `"`) {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return ErrInvalidRuntimeFormat
+		}
+		s = unquoted
+	}
+
+	runtime, err := ParseRuntime(s)
 	if err != nil {
-		return ErrInvalidRuntimeFormat
+		return err
 	}
 
 	// return custom type
-	*r = Runtime(i)
+	*r = runtime
 
 	return nil
 }
+
+// ParseRuntime reads a runtime written as a whole number of minutes, optionally followed by "mins" ("107",
+// "107 mins"), in hours and minutes ("1h 47m", "2h", "47m") or as an ISO 8601 duration of hours and minutes
+// ("PT1H47M"). Units are matched regardless of case. ErrNegativeRuntime and ErrRuntimeOverflow are returned for
+// runtimes in one of these forms which are below zero or do not fit a Runtime, ErrInvalidRuntimeFormat for anything
+// else.
+func ParseRuntime(s string) (Runtime, error) {
+	s = strings.TrimSpace(s)
+
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = strings.TrimSpace(s[1:])
+	}
+
+	var hours, minutes string
+	if m := runtimeMinutesRX.FindStringSubmatch(s); m != nil {
+		minutes = m[1]
+	} else if m := runtimeHoursRX.FindStringSubmatch(s); m != nil && (m[1] != "" || m[2] != "") {
+		hours, minutes = m[1], m[2]
+	} else if m := runtimeISO8601RX.FindStringSubmatch(s); m != nil && (m[1] != "" || m[2] != "") {
+		hours, minutes = m[1], m[2]
+	} else {
+		return 0, ErrInvalidRuntimeFormat
+	}
+
+	// The patterns only match digits, so failing to parse a number means it is out of range.
+	var total int64
+	overflow := false
+	if hours != "" {
+		h, err := strconv.ParseInt(hours, 10, 64)
+		overflow = err != nil || h > math.MaxInt32/60
+		total = h * 60
+	}
+	if minutes != "" && !overflow {
+		m, err := strconv.ParseInt(minutes, 10, 64)
+		overflow = err != nil || m > math.MaxInt32
+		total += m
+	}
+
+	switch {
+	case negative && (overflow || total > 0):
+		return 0, ErrNegativeRuntime
+	case overflow || total > math.MaxInt32:
+		return 0, ErrRuntimeOverflow
+	}
+
+	return Runtime(total), nil
+}
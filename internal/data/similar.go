@@ -0,0 +1,132 @@
+package data
+
+import (
+	"fmt"
+	"github.com/lib/pq"
+	"math"
+)
+
+// Weights of each part of the similarity score, which sum to 1. Each part is itself between 0 and 1.
+const (
+	similarityGenresWeight  = 0.4 // shared genres over all genres of the two movies
+	similarityYearWeight    = 0.2 // falls from 1 for the same year to 0 at SimilarYearWindow years apart
+	similarityRuntimeWeight = 0.1 // falls from 1 for the same runtime to 0 at similarityRuntimeWindow minutes apart
+	similarityRatingWeight  = 0.1 // closeness of the average ratings, only when both movies are rated
+	similarityPeopleWeight  = 0.2 // share of the movie's credited people also credited on the other movie
+)
+
+// SimilarYearWindow is the number of years either side of a movie within which movies sharing a genre are considered
+// similar. Along with movies sharing a credited person they are the only candidates scored, which keeps the score
+// from being computed for the whole catalogue, see SimilarTo.apply.
+const SimilarYearWindow = 20
+
+// similarityRuntimeWindow is the difference in minutes at which runtimes no longer add to the similarity score.
+const similarityRuntimeWindow = 60
+
+// SimilarTo describes the movie which others are compared against when MovieCriteria.Similar is set.
+type SimilarTo struct {
+	ID      int64
+	Genres  []string
+	Year    int32
+	Runtime Runtime
+	Rating  float32
+	People  []int64 // the distinct people credited on the movie
+}
+
+// NewSimilarTo returns the comparison for movie, given its credits.
+func NewSimilarTo(movie *Movie, credits []*Credit) *SimilarTo {
+	s := &SimilarTo{
+		ID:      movie.ID,
+		Genres:  copyStrings(movie.Genres),
+		Year:    movie.Year,
+		Runtime: movie.Runtime,
+		Rating:  movie.Rating,
+	}
+
+	seen := make(map[int64]bool)
+	for _, credit := range credits {
+		if !seen[credit.PersonID] {
+			seen[credit.PersonID] = true
+			s.People = append(s.People, credit.PersonID)
+		}
+	}
+
+	return s
+}
+
+// apply adds the conditions selecting the candidates for similarity to q, returning the expression scoring each row.
+// The score is rounded to four places and cast to real, so it compares exactly with the value held by a cursor.
+//
+// The candidates are shortlisted by a subquery before any are scored: movies sharing a genre within
+// SimilarYearWindow years, found through the genres GIN index and the year index, and movies sharing a credited
+// person, found through the person_id index on movie_credits. Written as a single OR across the two tables neither
+// index could be used, and every movie would be read to decide whether it is a candidate.
+func (s *SimilarTo) apply(q *filterQuery) string {
+	q.where(fmt.Sprintf("id <> %s", q.arg(s.ID)))
+
+	genres := q.arg(pq.Array(s.Genres))
+	year := q.arg(s.Year)
+
+	shortlist := fmt.Sprintf("SELECT id FROM movies WHERE genres && %s AND year BETWEEN %s - %d AND %s + %d",
+		genres, year, SimilarYearWindow, year, SimilarYearWindow)
+	people := "0"
+	if len(s.People) > 0 {
+		ids := q.arg(pq.Array(s.People))
+		shortlist += fmt.Sprintf("\n\tUNION\n\tSELECT movie_id FROM movie_credits WHERE person_id = ANY(%s)", ids)
+		people = fmt.Sprintf("(SELECT count(DISTINCT person_id) FROM movie_credits c WHERE c.movie_id = movies.id AND c.person_id = ANY(%s))::real / %d",
+			ids, len(s.People))
+	}
+	q.where(fmt.Sprintf("id IN (%s)", shortlist))
+
+	shared := fmt.Sprintf("(SELECT count(*) FROM unnest(genres) g WHERE g = ANY(%s))", genres)
+	parts := []string{
+		fmt.Sprintf("%v * %s::real / (cardinality(genres) + %d - %s)", similarityGenresWeight, shared, len(s.Genres), shared),
+		fmt.Sprintf("%v * GREATEST(0, 1 - abs(year - %s)::real / %d)", similarityYearWeight, year, SimilarYearWindow),
+		fmt.Sprintf("%v * GREATEST(0, 1 - abs(runtime - %s)::real / %d)", similarityRuntimeWeight, q.arg(s.Runtime), similarityRuntimeWindow),
+		fmt.Sprintf("%v * %s", similarityPeopleWeight, people),
+	}
+	if s.Rating > 0 {
+		parts = append(parts, fmt.Sprintf("%v * CASE WHEN rating_avg > 0 THEN 1 - abs(rating_avg - %s) / %d ELSE 0 END",
+			similarityRatingWeight, q.arg(s.Rating), RatingMax-1))
+	}
+
+	expression := parts[0]
+	for _, part := range parts[1:] {
+		expression += " + " + part
+	}
+	return fmt.Sprintf("round((%s)::numeric, 4)::real", expression)
+}
+
+// candidate reports whether movie is scored against s, sharing a genre within SimilarYearWindow years or sharing
+// sharedPeople credited people. It is the in-memory counterpart of the conditions added by apply.
+func (s *SimilarTo) candidate(movie *Movie, sharedPeople int) bool {
+	if movie.ID == s.ID {
+		return false
+	}
+	if sharedPeople > 0 {
+		return true
+	}
+	return containsAny(movie.Genres, s.Genres) && math.Abs(float64(movie.Year-s.Year)) <= SimilarYearWindow
+}
+
+// score computes the similarity of movie to s as the expression returned by apply does.
+func (s *SimilarTo) score(movie *Movie, sharedPeople int) float32 {
+	shared := 0
+	for _, genre := range movie.Genres {
+		if containsAll(s.Genres, []string{genre}) {
+			shared++
+		}
+	}
+
+	score := similarityGenresWeight * float64(shared) / float64(len(movie.Genres)+len(s.Genres)-shared)
+	score += similarityYearWeight * math.Max(0, 1-math.Abs(float64(movie.Year-s.Year))/SimilarYearWindow)
+	score += similarityRuntimeWeight * math.Max(0, 1-math.Abs(float64(movie.Runtime-s.Runtime))/similarityRuntimeWindow)
+	if len(s.People) > 0 {
+		score += similarityPeopleWeight * float64(sharedPeople) / float64(len(s.People))
+	}
+	if s.Rating > 0 && movie.Rating > 0 {
+		score += similarityRatingWeight * (1 - math.Abs(float64(movie.Rating-s.Rating))/(RatingMax-1))
+	}
+
+	return float32(math.Round(score*10000) / 10000)
+}
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimilarToApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		similar *SimilarTo
+		want    string // the shortlisting condition
+		args    int
+	}{
+		{
+			"genres and year",
+			&SimilarTo{ID: 1, Genres: []string{"drama"}, Year: 2000, Runtime: 100},
+			"id IN (SELECT id FROM movies WHERE genres && $2 AND year BETWEEN $3 - 20 AND $3 + 20)",
+			4,
+		},
+		{
+			"shared people",
+			&SimilarTo{ID: 1, Genres: []string{"drama"}, Year: 2000, Runtime: 100, Rating: 7, People: []int64{5, 6}},
+			"id IN (SELECT id FROM movies WHERE genres && $2 AND year BETWEEN $3 - 20 AND $3 + 20\n\tUNION\n\tSELECT movie_id FROM movie_credits WHERE person_id = ANY($4))",
+			6,
+		},
+	}
+
+	for _, tt := range tests {
+		var q filterQuery
+		score := tt.similar.apply(&q)
+
+		if len(q.conditions) != 2 || q.conditions[0] != "id <> $1" || q.conditions[1] != tt.want {
+			t.Errorf("%s: got conditions %q; want id <> $1 and %q", tt.name, q.conditions, tt.want)
+		}
+		if len(q.args) != tt.args {
+			t.Errorf("%s: got %d args; want %d", tt.name, len(q.args), tt.args)
+		}
+		if !strings.HasPrefix(score, "round((") {
+			t.Errorf("%s: got score %q; want it rounded", tt.name, score)
+		}
+	}
+}
+
+func TestSimilarToCandidateAndScore(t *testing.T) {
+	s := &SimilarTo{ID: 1, Genres: []string{"drama", "crime"}, Year: 2000, Runtime: 100, People: []int64{5, 6}}
+
+	tests := []struct {
+		name          string
+		movie         *Movie
+		shared        int
+		wantCandidate bool
+		wantScore     float32
+	}{
+		{"itself", &Movie{ID: 1, Genres: []string{"drama", "crime"}, Year: 2000, Runtime: 100}, 2, false, 0},
+		{"identical", &Movie{ID: 2, Genres: []string{"drama", "crime"}, Year: 2000, Runtime: 100}, 2, true, 0.9},
+		{"shared genre", &Movie{ID: 3, Genres: []string{"drama"}, Year: 2010, Runtime: 130}, 0, true, 0.2 + 0.1 + 0.05},
+		{"too far apart", &Movie{ID: 4, Genres: []string{"drama"}, Year: 2021, Runtime: 100}, 0, false, 0},
+		{"shared person only", &Movie{ID: 5, Genres: []string{"comedy"}, Year: 1950, Runtime: 200}, 1, true, 0.1},
+	}
+
+	for _, tt := range tests {
+		if got := s.candidate(tt.movie, tt.shared); got != tt.wantCandidate {
+			t.Errorf("%s: got candidate %v; want %v", tt.name, got, tt.wantCandidate)
+		}
+		if !tt.wantCandidate {
+			continue
+		}
+		if got := s.score(tt.movie, tt.shared); got != tt.wantScore {
+			t.Errorf("%s: got score %v; want %v", tt.name, got, tt.wantScore)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"movieDB/internal/validator"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrDuplicateTitle is returned when a movie already has an alternate title for the same language and region.
+var ErrDuplicateTitle = errors.New("duplicate title")
+
+var (
+	// LanguageRX matches an ISO 639 language code in lower case, such as "en" or "fil".
+	LanguageRX = regexp.MustCompile("^[a-z]{2,3}$")
+	// RegionRX matches an ISO 3166 country code in upper case, such as "US", or a UN M.49 area code such as "419".
+	RegionRX = regexp.MustCompile("^([A-Z]{2}|[0-9]{3})$")
+)
+
+// Title is an alternate title of a movie for a language, and optionally a region where the release title differs
+// from the rest of the language, such as the French title used in Quebec. Movie.Title remains the default.
+type Title struct {
+	ID       int64  `json:"id"`
+	MovieID  int64  `json:"movie_id"`
+	Title    string `json:"title"`
+	Language string `json:"language"`
+	Region   string `json:"region,omitempty"`
+}
+
+// Locale returns the language tag of the title, such as "fr" or "fr-CA".
+func (t *Title) Locale() string {
+	if t.Region == "" {
+		return t.Language
+	}
+	return t.Language + "-" + t.Region
+}
+
+// TitleModel holds the database pool for the movie_titles table.
+type TitleModel struct {
+	DB *sql.DB
+}
+
+// Insert adds an alternate title to a movie. ErrRecordNotFound is returned if the movie does not exist and
+// ErrDuplicateTitle if it already has a title for the language and region.
+func (m TitleModel) Insert(title *Title) error {
+	query := `
+	INSERT INTO movie_titles (movie_id, title, language, region)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id`
+
+	args := []interface{}{title.MovieID, title.Title, title.Language, title.Region}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&title.ID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "movie_titles" violates foreign key constraint "movie_titles_movie_id_fkey"`:
+			return ErrRecordNotFound
+		case err.Error() == `pq: duplicate key value violates unique constraint "movie_titles_movie_id_language_region_key"`:
+			return ErrDuplicateTitle
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a single alternate title from a movie.
+func (m TitleModel) Delete(movieID, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM movie_titles
+	WHERE id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForMovie lists the alternate titles of a movie ordered by language and region.
+func (m TitleModel) GetAllForMovie(movieID int64) ([]*Title, error) {
+	titles, err := m.GetAllForMovies([]int64{movieID})
+	if err != nil {
+		return nil, err
+	}
+
+	if titles[movieID] == nil {
+		return []*Title{}, nil
+	}
+	return titles[movieID], nil
+}
+
+// GetAllForMovies returns the alternate titles of several movies at once keyed by movie ID, each ordered by language
+// and region. Movies without alternate titles are absent from the map.
+func (m TitleModel) GetAllForMovies(movieIDs []int64) (map[int64][]*Title, error) {
+	query := `
+	SELECT id, movie_id, title, language, region
+	FROM movie_titles
+	WHERE movie_id = ANY($1)
+	ORDER BY movie_id, language, region, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	titles := make(map[int64][]*Title)
+	for rows.Next() {
+		var title Title
+		err := rows.Scan(&title.ID, &title.MovieID, &title.Title, &title.Language, &title.Region)
+		if err != nil {
+			return nil, err
+		}
+		titles[title.MovieID] = append(titles[title.MovieID], &title)
+	}
+
+	return titles, rows.Err()
+}
+
+// NormalizeLocale returns language in lower case and region in upper case, the forms stored in movie_titles.
+func NormalizeLocale(language, region string) (string, string) {
+	return strings.ToLower(language), strings.ToUpper(region)
+}
+
+// ValidateTitle checks the fields of an alternate title before it is saved. The language and region must already be
+// normalised with NormalizeLocale.
+func ValidateTitle(v *validator.Validator, title *Title) {
+	v.Check(title.Title != "", "title", "must be provided")
+	v.Check(len(title.Title) <= 500, "title", "must not be longer than 500 bytes")
+
+	v.Check(title.Language != "", "language", "must be provided")
+	v.Check(validator.Matches(title.Language, LanguageRX), "language", "must be an ISO 639 language code")
+
+	if title.Region != "" {
+		v.Check(validator.Matches(title.Region, RegionRX), "region", "must be an ISO 3166 country code or UN M.49 area code")
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"crypto/sha256"
 	"encoding/base32"
 	"movieDB/internal/validator"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 //ScopeActivation provides the string for Activation context.
@@ -15,6 +17,27 @@ const ScopeActivation = "activation"
 //ScopeAuthentication provides the string for Authentication context.
 const ScopeAuthentication = "authentication"
 
+// ScopePasswordReset provides the string for the Password Reset context.
+const ScopePasswordReset = "password-reset"
+
+// SessionTouchInterval is how often the last use of a session is recorded. Recording every request would turn each
+// authenticated read into a write.
+const SessionTouchInterval = time.Minute
+
+// maxUserAgentLength bounds the user agent stored for a session in bytes, longer values are cut short.
+const maxUserAgentLength = 256
+
+// Session describes an authentication token as it is listed to its user. The token itself is never shown.
+type Session struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Expiry     time.Time `json:"expiry"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"` // the session of the token the sessions were listed with
+}
+
 //ValidateTokenPlaintext validates input for the token in plaintext. If a case fails then the Validator adds an error
 // entry to the Validator map
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlainText string) {
@@ -27,28 +50,38 @@ func ValidateTokenPlaintext(v *validator.Validator, tokenPlainText string) {
 // it is ESSENTIAL we reference crypto/rand and not math/rand.
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
 
-	randomBytes := make([]byte, 16)
-	_, err := rand.Read(randomBytes)
+	plainText, hash, err := randomToken()
 	if err != nil {
 		return nil, err
 	}
 
-	plainText := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
-
-	// sha256 returns a BYTE Array hash with a fixed 32 byte size. Converted to slice below.
-	hash := sha256.Sum256([]byte(plainText))
-
 	token := &Token{
 		UserID:    userID,
 		Expiry:    time.Now().Add(ttl),
 		Scope:     scope,
 		Plaintext: plainText,
-		Hash:      hash[:], // Convert byte array ([32]byte) to slice
+		Hash:      hash,
 	}
 
 	return token, nil
 }
 
+// randomToken returns a random 26 character plaintext along with the SHA256 hash under which it is stored.
+func randomToken() (string, []byte, error) {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plainText := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	// sha256 returns a BYTE Array hash with a fixed 32 byte size. Converted to slice below.
+	hash := sha256.Sum256([]byte(plainText))
+
+	return plainText, hash[:], nil
+}
+
 //New is a function which generates a new token and then, assuming no error, inserts it to the db.
 func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token, err := generateToken(userID, ttl, scope)
@@ -60,20 +93,54 @@ func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token,
 	return token, err
 }
 
+// NewSession generates a new authentication token for a client, recording its address and user agent so that the
+// token can be listed as a Session, and inserts it.
+func (m TokenModel) NewSession(userID int64, ttl time.Duration, ip, userAgent string) (*Token, error) {
+	token, err := generateSession(userID, ttl, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(token)
+	return token, err
+}
+
+// generateSession generates an authentication token holding the client details of a Session.
+func generateSession(userID int64, ttl time.Duration, ip, userAgent string) (*Token, error) {
+	token, err := generateToken(userID, ttl, ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header is client supplied so it may not be valid UTF-8, which PostgreSQL refuses to store in a text column.
+	// It is cut at the start of a character so as not to leave half of one behind.
+	userAgent = strings.ToValidUTF8(userAgent, "\uFFFD")
+	if len(userAgent) > maxUserAgentLength {
+		cut := maxUserAgentLength
+		for !utf8.RuneStart(userAgent[cut]) {
+			cut--
+		}
+		userAgent = userAgent[:cut]
+	}
+	token.IP, token.UserAgent = ip, userAgent
+
+	return token, nil
+}
+
 //Insert adds a token to the tokens table, it stores a SHA256 Hash of the plaintext token
-// and a scope indicating whether we are authorizing or authenticating a user.
+// and a scope indicating whether we are authorizing or authenticating a user. The ID, CreatedAt and LastUsedAt
+// fields are populated.
 func (m TokenModel) Insert(token *Token) error {
-	query := `INSERT INTO tokens (hash, user_id, expiry, scope)
-	VALUES ($1, $2, $3, $4)`
+	query := `INSERT INTO tokens (hash, user_id, expiry, scope, ip, user_agent)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at, last_used_at`
 
-	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
+	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope, token.IP, token.UserAgent}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	// Execute query without returning any rows
-	_, err := m.DB.ExecContext(ctx, query, args...) // ignore Result(lastInsertID)
-	return err
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.CreatedAt, &token.LastUsedAt)
 }
 
 // DeleteAllForUser Delete all tokens for User given their User.ID and a scope (which may be used
@@ -88,3 +155,104 @@ func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// Touch records that an authentication token has just been used. The write is skipped when the last use was recorded
+// within SessionTouchInterval.
+func (m TokenModel) Touch(tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `UPDATE tokens
+	SET last_used_at = NOW()
+	WHERE hash = $1 AND scope = $2 AND last_used_at < $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, tokenHash[:], ScopeAuthentication, time.Now().Add(-SessionTouchInterval))
+	return err
+}
+
+// Delete removes a single token given its plaintext, returning ErrRecordNotFound if there is no such token.
+func (m TokenModel) Delete(scope, tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `DELETE FROM tokens
+	WHERE hash = $1 AND scope = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, tokenHash[:], scope)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetSessionsForUser lists the unexpired authentication tokens of a user as sessions, the most recently used first.
+// The session of currentPlaintext, the token of the request, is marked as Current.
+func (m TokenModel) GetSessionsForUser(userID int64, currentPlaintext string) ([]*Session, error) {
+	currentHash := sha256.Sum256([]byte(currentPlaintext))
+
+	query := `SELECT id, created_at, last_used_at, expiry, ip, user_agent, hash = $3
+	FROM tokens
+	WHERE user_id = $1 AND scope = $2 AND expiry > NOW()
+	ORDER BY last_used_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, ScopeAuthentication, currentHash[:])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt, &session.Expiry, &session.IP,
+			&session.UserAgent, &session.Current)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// DeleteSession revokes a single session of a user, returning ErrRecordNotFound if the user has no such session.
+func (m TokenModel) DeleteSession(userID, id int64) error {
+	query := `DELETE FROM tokens
+	WHERE id = $1 AND user_id = $2 AND scope = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID, ScopeAuthentication)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestGenerateSessionUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"short", "curl/8.0", "curl/8.0"},
+		{"long", strings.Repeat("a", maxUserAgentLength+10), strings.Repeat("a", maxUserAgentLength)},
+		{"multibyte at the limit", strings.Repeat("a", maxUserAgentLength-1) + "é", strings.Repeat("a", maxUserAgentLength-1)},
+		{"invalid UTF-8", "agent\xff/1.0", "agent�/1.0"},
+	}
+
+	for _, tt := range tests {
+		token, err := generateSession(1, time.Hour, "192.0.2.1", tt.userAgent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.UserAgent != tt.want {
+			t.Errorf("%s: got user agent %q; want %q", tt.name, token.UserAgent, tt.want)
+		}
+		if !utf8.ValidString(token.UserAgent) || len(token.UserAgent) > maxUserAgentLength {
+			t.Errorf("%s: got %d bytes of user agent, valid UTF-8 %v", tt.name, len(token.UserAgent), utf8.ValidString(token.UserAgent))
+		}
+	}
+}
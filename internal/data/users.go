@@ -180,7 +180,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	// check that we have not exceeded the TTL of the token. Todo: Delete old tokens!
 
 	query := `
-	SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+	SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version,
+	       tokens.last_used_at
 	FROM users
 	INNER JOIN tokens
 	ON users.ID = tokens.user_id
@@ -205,6 +206,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.TokenLastUsedAt,
 	)
 
 	if err != nil {
@@ -218,3 +220,27 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 
 	return &user, nil
 }
+
+// DeleteUnactivated removes the accounts which were registered before the given time and never activated, returning
+// how many were removed. An account holding an unexpired activation token is kept, so a token sent by a late resend
+// can still be used. Tokens and permissions are removed with the accounts by ON DELETE CASCADE, an unactivated
+// account cannot have rated movies or kept lists.
+func (m *UserModel) DeleteUnactivated(before time.Time) (int64, error) {
+	query := `
+	DELETE FROM users
+	WHERE activated = false AND created_at < $1
+	AND NOT EXISTS (
+		SELECT 1 FROM tokens
+		WHERE tokens.user_id = users.id AND tokens.scope = $2 AND tokens.expiry > NOW()
+	)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, before, ScopeActivation)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,435 @@
+/*
+Package jsonpatch applies JSON Patch (RFC 6902) and JSON Merge Patch (RFC 7396) documents to JSON values. Documents
+are decoded with json.Number so that numbers pass through unchanged.
+*/
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned for a patch which is not a well-formed JSON Patch document.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPathNotFound is returned when an operation refers to a location which does not exist in the document.
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTestFailed is returned when a test operation finds a different value at its path.
+	ErrTestFailed = errors.New("test failed")
+)
+
+// operation is a single operation of a JSON Patch document.
+type operation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// Apply applies the JSON Patch patch to doc, returning the patched document. The operations are applied in order and
+// the patch fails as a whole if any of them does.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []operation
+	if err := decode(patch, &ops); err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		if errors.As(err, &unmarshalTypeError) {
+			return nil, fmt.Errorf("%w: must be an array of operations", ErrInvalidPatch)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var value interface{}
+	if err := decode(doc, &value); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		value, err = op.apply(value)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return json.Marshal(value)
+}
+
+// MergePatch applies the JSON Merge Patch patch to doc, returning the patched document. Members of patch replace
+// those of doc, objects are merged recursively and null removes a member.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var p interface{}
+	if err := decode(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var value interface{}
+	if err := decode(doc, &value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(value, p))
+}
+
+// merge implements the MergePatch algorithm of RFC 7396.
+func merge(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{})
+	}
+
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = merge(object[name], value)
+		}
+	}
+	return object
+}
+
+// apply performs the operation on doc, returning the resulting document.
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: %q operation requires a path", ErrInvalidPatch, op.Op)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %q operation requires a value", ErrInvalidPatch, op.Op)
+		}
+		var value interface{}
+		if err := decode(*op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, fmt.Errorf("%w: %s does not hold the given value", ErrTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %q operation requires from", ErrInvalidPatch, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		if op.Op == "move" {
+			if from.contains(path) && len(path) > len(from) {
+				return nil, fmt.Errorf("%w: cannot move %s into one of its children", ErrInvalidPatch, *op.From)
+			}
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			value = clone(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// escaper and unescaper convert reference tokens to and from their escaped form within a JSON Pointer. ~1 is
+// unescaped before ~0 so that "~01" becomes "~1" rather than "/".
+var (
+	escaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// pointer is a parsed JSON Pointer (RFC 6901). The empty pointer refers to the whole document.
+type pointer []string
+
+// parsePointer parses s, unescaping ~1 and ~0 in each reference token. A ~ followed by anything else is an error.
+func parsePointer(s string) (pointer, error) {
+	if s == "" {
+		return pointer{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("%w: path %q must be empty or start with /", ErrInvalidPatch, s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		if strings.Count(token, "~") != strings.Count(token, "~0")+strings.Count(token, "~1") {
+			return nil, fmt.Errorf("%w: path %q must only use ~ in the escapes ~0 and ~1", ErrInvalidPatch, s)
+		}
+		tokens[i] = unescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+// contains reports whether other is p or lies beneath it.
+func (p pointer) contains(other pointer) bool {
+	if len(other) < len(p) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the pointer in its escaped form.
+func (p pointer) String() string {
+	var sb strings.Builder
+	for _, token := range p {
+		sb.WriteString("/")
+		sb.WriteString(escaper.Replace(token))
+	}
+	return sb.String()
+}
+
+// get returns the value at path.
+func get(doc interface{}, path pointer) (interface{}, error) {
+	for i, token := range path {
+		var err error
+		doc, err = child(doc, token, path[:i+1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// add inserts value at path. Within an array the elements from the index on are shifted along, and the index "-"
+// appends. A member of an object is added or replaced.
+func add(doc interface{}, path pointer, value interface{}) (interface{}, error) {
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			i := len(c)
+			if token != "-" {
+				var err error
+				if i, err = index(token, len(c)+1, path); err != nil {
+					return nil, err
+				}
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		default:
+			return nil, fmt.Errorf("%w: %s is not within an object or array", ErrPathNotFound, path)
+		}
+	}, value)
+}
+
+// replace replaces the existing value at path.
+func replace(doc interface{}, path pointer, value interface{}) (interface{}, error) {
+	return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		if _, err := child(container, token, path); err != nil {
+			return nil, err
+		}
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		default:
+			a := container.([]interface{})
+			i, _ := index(token, len(a), path)
+			a[i] = value
+			return a, nil
+		}
+	}, value)
+}
+
+// remove removes the value at path, returning the document and the value removed.
+func remove(doc interface{}, path pointer) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	var removed interface{}
+	doc, err := update(doc, path, func(container interface{}, token string) (interface{}, error) {
+		value, err := child(container, token, path)
+		if err != nil {
+			return nil, err
+		}
+		removed = value
+
+		switch c := container.(type) {
+		case map[string]interface{}:
+			delete(c, token)
+			return c, nil
+		default:
+			a := container.([]interface{})
+			i, _ := index(token, len(a), path)
+			return append(a[:i], a[i+1:]...), nil
+		}
+	}, nil)
+
+	return doc, removed, err
+}
+
+// update calls fn with the container holding the last token of path and that token, replacing the container with
+// the one returned. As arrays may be reallocated, each container on the way down is replaced in its parent. An empty
+// path replaces the whole document with root.
+func update(doc interface{}, path pointer, fn func(container interface{}, token string) (interface{}, error), root interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	next, err := child(doc, path[0], path[:1])
+	if err != nil {
+		return nil, err
+	}
+	next, err = update(next, path[1:], fn, root)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		c[path[0]] = next
+	case []interface{}:
+		i, _ := index(path[0], len(c), path[:1])
+		c[i] = next
+	}
+	return doc, nil
+}
+
+// child returns the member or element of container named by token, at is the path to it for errors.
+func child(container interface{}, token string, at pointer) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		value, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, at)
+		}
+		return value, nil
+	case []interface{}:
+		i, err := index(token, len(c), at)
+		if err != nil {
+			return nil, err
+		}
+		return c[i], nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, at)
+	}
+}
+
+// index parses an array index, which must be below limit. Leading zeros are not allowed.
+func index(token string, limit int, at pointer) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || strings.HasPrefix(token, "+") {
+		return 0, fmt.Errorf("%w: %s is not a valid array index", ErrPathNotFound, at)
+	}
+	if i >= limit {
+		return 0, fmt.Errorf("%w: %s is out of range", ErrPathNotFound, at)
+	}
+	return i, nil
+}
+
+// equal reports whether two decoded values are the same JSON value, comparing numbers by value.
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, ok := b[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		return errA == nil && errB == nil && x == y
+	default:
+		return a == b
+	}
+}
+
+// clone returns a deep copy of a decoded value, so that a copied value is not shared with its source.
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for name, member := range v {
+			c[name] = clone(member)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, element := range v {
+			c[i] = clone(element)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// decode decodes a single JSON value from b, using json.Number for numbers.
+func decode(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("must only contain a single JSON value")
+	}
+	return nil
+}
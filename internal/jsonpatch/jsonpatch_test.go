@@ -0,0 +1,254 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// assertJSON fails the test unless got and want hold the same JSON value.
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var a, b interface{}
+	if err := json.Unmarshal(got, &a); err != nil {
+		t.Fatalf("got invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatalf("invalid expected JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+// TestApplyRFC6902 runs the examples of RFC 6902 appendix A.
+func TestApplyRFC6902(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string // empty when the patch must fail
+	}{
+		{
+			"A.1 adding an object member",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			"A.2 adding an array element",
+			`{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			"A.3 removing an object member",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`,
+		},
+		{
+			"A.4 removing an array element",
+			`{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`,
+		},
+		{
+			"A.5 replacing a value",
+			`{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			"A.6 moving a value",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			"A.7 moving an array element",
+			`{"foo":["all","grass","cows","eat"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			"A.8 testing a value: success",
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			"A.9 testing a value: error",
+			`{"baz":"qux"}`,
+			`[{"op":"test","path":"/baz","value":"bar"}]`,
+			``,
+		},
+		{
+			"A.10 adding a nested member object",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		{
+			"A.11 ignoring unrecognized elements",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			`{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			"A.12 adding to a nonexistent target",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			``,
+		},
+		{
+			"A.13 invalid JSON patch document",
+			`{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`,
+			``,
+		},
+		{
+			"A.14 ~ escape ordering",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`,
+			`{"/":9,"~1":10}`,
+		},
+		{
+			"A.15 comparing strings and numbers",
+			`{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":"10"}]`,
+			``,
+		},
+		{
+			"A.16 adding an array value",
+			`{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("got %s; want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	doc := `{"a/b":1,"m~n":2,"list":[1,2,3],"nested":{"x":[{"y":1}]}}`
+
+	tests := []struct {
+		name  string
+		patch string
+		want  error
+	}{
+		{"test of a different value", `[{"op":"test","path":"/list","value":[1,2]}]`, ErrTestFailed},
+		{"test of a missing member", `[{"op":"test","path":"/missing","value":1}]`, ErrPathNotFound},
+		{"test of a number as a string", `[{"op":"test","path":"/a~1b","value":"1"}]`, ErrTestFailed},
+		{"test after a change", `[{"op":"replace","path":"/m~0n","value":3},{"op":"test","path":"/m~0n","value":2}]`, ErrTestFailed},
+		{"- index in test", `[{"op":"test","path":"/list/-","value":3}]`, ErrPathNotFound},
+		{"- index in remove", `[{"op":"remove","path":"/list/-"}]`, ErrPathNotFound},
+		{"- index in replace", `[{"op":"replace","path":"/list/-","value":4}]`, ErrPathNotFound},
+		{"- index within a path", `[{"op":"add","path":"/nested/x/-/y","value":2}]`, ErrPathNotFound},
+		{"index past the end", `[{"op":"add","path":"/list/4","value":4}]`, ErrPathNotFound},
+		{"index with a leading zero", `[{"op":"remove","path":"/list/01"}]`, ErrPathNotFound},
+		{"unescaped / in a member name", `[{"op":"remove","path":"/a/b"}]`, ErrPathNotFound},
+		{"unescaped ~ in a member name", `[{"op":"test","path":"/m~n","value":2}]`, ErrInvalidPatch},
+		{"~ at the end of a token", `[{"op":"remove","path":"/list~"}]`, ErrInvalidPatch},
+		{"~1 read as / rather than ~", `[{"op":"remove","path":"/m~1n"}]`, ErrPathNotFound},
+		{"path without a leading /", `[{"op":"remove","path":"list"}]`, ErrInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/x"}]`, ErrInvalidPatch},
+		{"missing from", `[{"op":"copy","path":"/x"}]`, ErrInvalidPatch},
+		{"unknown operation", `[{"op":"increment","path":"/list/0"}]`, ErrInvalidPatch},
+		{"move into a child", `[{"op":"move","from":"/nested","path":"/nested/x/0/z"}]`, ErrInvalidPatch},
+		{"not an array", `{"op":"remove","path":"/list"}`, ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(doc), []byte(tt.patch))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %s and error %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEscapes(t *testing.T) {
+	doc := `{"a/b":1,"m~n":2,"~1":3}`
+	patch := `[
+		{"op":"replace","path":"/a~1b","value":10},
+		{"op":"move","from":"/m~0n","path":"/m~0~1n"},
+		{"op":"test","path":"/~01","value":3}
+	]`
+
+	got, err := Apply([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, got, `{"a/b":10,"m~/n":2,"~1":3}`)
+}
+
+func TestApplyCopyIsNotShared(t *testing.T) {
+	patch := `[
+		{"op":"copy","from":"/a","path":"/b"},
+		{"op":"add","path":"/b/-","value":2}
+	]`
+
+	got, err := Apply([]byte(`{"a":[1]}`), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, got, `{"a":[1],"b":[1,2]}`)
+}
+
+// TestMergePatchRFC7396 runs the examples of RFC 7396 appendix A.
+func TestMergePatchRFC7396(t *testing.T) {
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("got error %v for a malformed patch; want ErrInvalidPatch", err)
+	}
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS movies_title_simple_idx;
+DROP INDEX IF EXISTS movies_title_english_idx;
+DROP INDEX IF EXISTS movies_title_french_idx;
+DROP INDEX IF EXISTS movies_title_german_idx;
+DROP INDEX IF EXISTS movies_title_spanish_idx;
+CREATE INDEX IF NOT EXISTS movies_title_idx ON movies USING GIN (to_tsvector('simple', title));
@@ -0,0 +1,8 @@
+-- Replace the single 'simple' title index with one per text search configuration in data.SearchConfigs. The
+-- expressions must match those built by MovieModel.GetAll exactly for the planner to use them.
+DROP INDEX IF EXISTS movies_title_idx;
+CREATE INDEX IF NOT EXISTS movies_title_simple_idx ON movies USING GIN (to_tsvector('simple', title));
+CREATE INDEX IF NOT EXISTS movies_title_english_idx ON movies USING GIN (to_tsvector('english', title));
+CREATE INDEX IF NOT EXISTS movies_title_french_idx ON movies USING GIN (to_tsvector('french', title));
+CREATE INDEX IF NOT EXISTS movies_title_german_idx ON movies USING GIN (to_tsvector('german', title));
+CREATE INDEX IF NOT EXISTS movies_title_spanish_idx ON movies USING GIN (to_tsvector('spanish', title));
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS movie_revisions
+(
+    id         bigserial PRIMARY KEY,
+    movie_id   bigint                      NOT NULL REFERENCES movies ON DELETE CASCADE,
+    version    integer                     NOT NULL,
+    action     text                        NOT NULL,
+    snapshot   jsonb                       NOT NULL,
+    user_id    bigint                      REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (movie_id, version)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS movies_rating_avg_id_idx;
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS rating_avg,
+    DROP COLUMN IF EXISTS rating_total,
+    DROP COLUMN IF EXISTS rating_count;
+DROP TABLE IF EXISTS ratings;
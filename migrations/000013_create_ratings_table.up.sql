@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS ratings
+(
+    id         bigserial PRIMARY KEY,
+    movie_id   bigint                      NOT NULL REFERENCES movies ON DELETE CASCADE,
+    user_id    bigint                      NOT NULL REFERENCES users ON DELETE CASCADE,
+    score      integer                     NOT NULL CHECK (score BETWEEN 1 AND 10),
+    review     text                        NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version    integer                     NOT NULL DEFAULT 1,
+    UNIQUE (movie_id, user_id)
+);
+
+-- The aggregates are maintained by RatingModel. rating_avg is 0 for unrated movies, below the lowest score, so it
+-- needs no NULL handling when sorting or filtering.
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS rating_count integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_total integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_avg   real    NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS movies_rating_avg_id_idx ON movies (rating_avg, id);
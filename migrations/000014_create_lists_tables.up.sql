@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS lists
+(
+    id         bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id    bigint                      NOT NULL REFERENCES users ON DELETE CASCADE,
+    name       text                        NOT NULL,
+    share_hash bytea UNIQUE,
+    version    integer                     NOT NULL DEFAULT 1,
+    UNIQUE (user_id, name)
+);
+
+-- Entries of a movie in the trash are hidden by ListModel; purging the movie removes them through the cascade.
+CREATE TABLE IF NOT EXISTS list_entries
+(
+    list_id  bigint                      NOT NULL REFERENCES lists ON DELETE CASCADE,
+    movie_id bigint                      NOT NULL REFERENCES movies ON DELETE CASCADE,
+    position integer                     NOT NULL,
+    notes    text                        NOT NULL DEFAULT '',
+    added_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (list_id, movie_id)
+);
+
+INSERT INTO permissions (code)
+VALUES ('lists:read'),
+       ('lists:write');
+
+-- Existing users are given the permissions which registerUserHandler now grants to new ones.
+INSERT INTO users_permissions
+SELECT users.id, permissions.id
+FROM users, permissions
+WHERE permissions.code IN ('lists:read', 'lists:write')
+ON CONFLICT DO NOTHING;
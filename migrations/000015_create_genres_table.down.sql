@@ -0,0 +1,2 @@
+-- Movies keep their canonical slugs, the spellings they replaced are not recorded.
+DROP TABLE IF EXISTS genres;
@@ -0,0 +1,65 @@
+CREATE TABLE IF NOT EXISTS genres
+(
+    id      bigserial PRIMARY KEY,
+    slug    text    NOT NULL UNIQUE,
+    name    text    NOT NULL,
+    aliases text[]  NOT NULL DEFAULT '{}',
+    version integer NOT NULL DEFAULT 1
+);
+
+-- Serves the aliases && $1 uniqueness check in GenreModel.
+CREATE INDEX IF NOT EXISTS genres_aliases_idx ON genres USING GIN (aliases);
+
+INSERT INTO genres (slug, name, aliases)
+VALUES ('action', 'Action', '{}'),
+       ('adventure', 'Adventure', '{}'),
+       ('animation', 'Animation', '{animated,cartoon}'),
+       ('biography', 'Biography', '{biopic}'),
+       ('comedy', 'Comedy', '{}'),
+       ('crime', 'Crime', '{}'),
+       ('documentary', 'Documentary', '{doc}'),
+       ('drama', 'Drama', '{}'),
+       ('family', 'Family', '{}'),
+       ('fantasy', 'Fantasy', '{}'),
+       ('history', 'History', '{historical}'),
+       ('horror', 'Horror', '{}'),
+       ('music', 'Music', '{}'),
+       ('musical', 'Musical', '{}'),
+       ('mystery', 'Mystery', '{}'),
+       ('romance', 'Romance', '{romantic}'),
+       ('science-fiction', 'Science Fiction', '{sci-fi,scifi,sf}'),
+       ('sport', 'Sport', '{sports}'),
+       ('thriller', 'Thriller', '{suspense}'),
+       ('war', 'War', '{}'),
+       ('western', 'Western', '{}')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Existing values are normalised as data.NormalizeGenre does: lower case, with each run of characters which are not
+-- letters or digits replaced by a hyphen. Values which then match no slug or alias become genres of their own, so
+-- no movie loses a genre; they can be merged into the seeded ones afterwards through the API.
+CREATE TEMPORARY TABLE movie_genre_values AS
+SELECT m.id                                                                   AS movie_id,
+       u.ord,
+       trim(BOTH '-' FROM regexp_replace(lower(u.value), '[^[:alnum:]]+', '-', 'g')) AS normalized
+FROM movies m,
+     unnest(m.genres) WITH ORDINALITY AS u(value, ord);
+
+INSERT INTO genres (slug, name)
+SELECT DISTINCT v.normalized, initcap(replace(v.normalized, '-', ' '))
+FROM movie_genre_values v
+WHERE v.normalized <> ''
+  AND NOT EXISTS(SELECT 1 FROM genres g WHERE g.slug = v.normalized OR v.normalized = ANY (g.aliases))
+ON CONFLICT (slug) DO NOTHING;
+
+-- Replace each array with the canonical slugs in their original order, dropping the duplicates which different
+-- spellings of the same genre leave behind.
+UPDATE movies m
+SET genres = ARRAY(SELECT g.slug
+                   FROM movie_genre_values v
+                            INNER JOIN genres g ON g.slug = v.normalized OR v.normalized = ANY (g.aliases)
+                   WHERE v.movie_id = m.id
+                   GROUP BY g.slug
+                   ORDER BY min(v.ord))
+WHERE EXISTS(SELECT 1 FROM movie_genre_values v WHERE v.movie_id = m.id);
+
+DROP TABLE movie_genre_values;
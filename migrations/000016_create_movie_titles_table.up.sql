@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS movie_titles
+(
+    id       bigserial PRIMARY KEY,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    title    text   NOT NULL,
+    language text   NOT NULL,
+    region   text   NOT NULL DEFAULT '',
+    UNIQUE (movie_id, language, region)
+);
+
+-- One index per text search configuration in data.SearchConfigs, matching the expressions built by
+-- MovieCriteria.apply as the title indexes of migration 000008 do.
+CREATE INDEX IF NOT EXISTS movie_titles_title_simple_idx ON movie_titles USING GIN (to_tsvector('simple', title));
+CREATE INDEX IF NOT EXISTS movie_titles_title_english_idx ON movie_titles USING GIN (to_tsvector('english', title));
+CREATE INDEX IF NOT EXISTS movie_titles_title_french_idx ON movie_titles USING GIN (to_tsvector('french', title));
+CREATE INDEX IF NOT EXISTS movie_titles_title_german_idx ON movie_titles USING GIN (to_tsvector('german', title));
+CREATE INDEX IF NOT EXISTS movie_titles_title_spanish_idx ON movie_titles USING GIN (to_tsvector('spanish', title));
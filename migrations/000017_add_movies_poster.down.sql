@@ -0,0 +1,4 @@
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS poster_sizes;
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS poster;
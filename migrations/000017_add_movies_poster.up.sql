@@ -0,0 +1,5 @@
+-- poster holds the file name of the current upload, poster_sizes the sizes of it stored so far in the blob store.
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS poster text NOT NULL DEFAULT '';
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS poster_sizes text[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS movies_title_trgm_idx;
+DROP INDEX IF EXISTS movies_external_wikidata_key;
+DROP INDEX IF EXISTS movies_external_tmdb_key;
+DROP INDEX IF EXISTS movies_external_imdb_key;
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS external_ids;
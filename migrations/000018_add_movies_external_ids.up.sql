@@ -0,0 +1,14 @@
+-- pg_trgm provides the similarity used by MovieModel.FindDuplicates. It is a trusted extension, so the database
+-- owner may create it.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE movies
+    ADD COLUMN IF NOT EXISTS external_ids jsonb NOT NULL DEFAULT '{}';
+
+-- One unique index per source in data.ExternalSources. The names must start with movies_external_ for the violation
+-- to be reported as ErrDuplicateExternalID.
+CREATE UNIQUE INDEX IF NOT EXISTS movies_external_imdb_key ON movies ((external_ids ->> 'imdb'));
+CREATE UNIQUE INDEX IF NOT EXISTS movies_external_tmdb_key ON movies ((external_ids ->> 'tmdb'));
+CREATE UNIQUE INDEX IF NOT EXISTS movies_external_wikidata_key ON movies ((external_ids ->> 'wikidata'));
+
+CREATE INDEX IF NOT EXISTS movies_title_trgm_idx ON movies USING GIN (title gin_trgm_ops);
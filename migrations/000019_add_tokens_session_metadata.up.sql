@@ -0,0 +1,10 @@
+-- Authentication tokens are listed to their users as sessions, identified by id as the hash must stay private.
+-- last_used_at is updated by TokenModel.Touch at most once per data.SessionTouchInterval.
+ALTER TABLE tokens
+    ADD COLUMN IF NOT EXISTS id           bigserial UNIQUE,
+    ADD COLUMN IF NOT EXISTS created_at   timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS last_used_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS ip           text                        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS user_agent   text                        NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS tokens_user_id_scope_idx ON tokens (user_id, scope);